@@ -0,0 +1,283 @@
+package godge
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// The maximum length of a symlink's target.
+const maxSymlinkTarget = 4096
+
+// ArchiveLimits restricts the content of the submitted archives. Zero values mean
+// no limit. Archives violating the limits are rejected when they're submitted.
+type ArchiveLimits struct {
+	// The maximum total size of the extracted files in bytes.
+	MaxTotalBytes int64 `json:"maxTotalBytes"`
+	// The maximum size of a single extracted file in bytes.
+	MaxFileBytes int64 `json:"maxFileBytes"`
+	// The maximum number of files and dirs in the archive.
+	MaxEntries int `json:"maxEntries"`
+	// Whether symlinks are allowed. Allowed symlinks must be relative and point to a
+	// path inside the archive.
+	AllowSymlinks bool `json:"allowSymlinks"`
+}
+
+// DefaultArchiveLimits are the archive limits used when the server's ArchiveLimits
+// aren't changed.
+var DefaultArchiveLimits = ArchiveLimits{
+	MaxTotalBytes: 100 * 1024 * 1024,
+	MaxFileBytes:  20 * 1024 * 1024,
+	MaxEntries:    10000,
+}
+
+// archiveEntryFunc is called with each of the archive's entries, along with its
+// cleaned path and a reader of its content bounded by the limits.
+type archiveEntryFunc func(f *zip.File, name string, r io.Reader) error
+
+// validateArchive checks that the archive respects the limits.
+func validateArchive(b []byte, limits ArchiveLimits) error {
+	return walkArchive(b, limits, func(f *zip.File, name string, r io.Reader) error {
+		_, err := io.Copy(ioutil.Discard, r)
+		return err
+	})
+}
+
+// extractArchive extracts the archive into dir, enforcing the limits.
+func extractArchive(b []byte, dir string, limits ArchiveLimits) error {
+	return walkArchive(b, limits, func(f *zip.File, name string, r io.Reader) error {
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			return os.MkdirAll(target, 0755)
+		case mode&os.ModeSymlink != 0:
+			link, err := ioutil.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			return os.Symlink(string(link), target)
+		default:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0600)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, r); err != nil {
+				out.Close()
+				return err
+			}
+			return out.Close()
+		}
+	})
+}
+
+// walkArchive calls fn with each of the archive's entries. It fails if an entry
+// violates the limits, or if its path points outside the archive.
+func walkArchive(b []byte, limits ArchiveLimits, fn archiveEntryFunc) error {
+	r := bytes.NewReader(b)
+	zr, err := zip.NewReader(r, r.Size())
+	if err != nil {
+		return fmt.Errorf("invalid zip archive: %v", err)
+	}
+	if limits.MaxEntries > 0 && len(zr.File) > limits.MaxEntries {
+		return fmt.Errorf("the archive has %v entries, the maximum is %v", len(zr.File), limits.MaxEntries)
+	}
+	symlinks, err := archiveSymlinks(zr, limits)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, f := range zr.File {
+		name, err := archiveEntryPath(f.Name)
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			// The root of the archive.
+			continue
+		}
+		// Entries under a symlink would be written wherever the symlink points to,
+		// regardless of their checked path.
+		if link := symlinkParent(name, symlinks); link != "" {
+			return fmt.Errorf("%v is inside the symlink %v", name, link)
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			if err := checkSymlink(f, name, symlinks); err != nil {
+				return err
+			}
+		} else if symlinks[name] {
+			return fmt.Errorf("%v is both a symlink and a file in the archive", name)
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %v: %v", name, err)
+		}
+		// The sizes in the archive's headers can't be trusted, so the actual content
+		// is counted while it's read.
+		cr := &countingReader{r: rc}
+		var content io.Reader = cr
+		if max := remainingBytes(limits, total); max >= 0 {
+			content = io.LimitReader(cr, max+1)
+		}
+		err = fn(f, name, content)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("failed to extract %v: %v", name, err)
+		}
+		if limits.MaxFileBytes > 0 && cr.n > limits.MaxFileBytes {
+			return fmt.Errorf("%v is larger than the maximum file size of %v", name, formatBytes(limits.MaxFileBytes))
+		}
+		total += cr.n
+		if limits.MaxTotalBytes > 0 && total > limits.MaxTotalBytes {
+			return fmt.Errorf("the extracted archive is larger than the maximum of %v", formatBytes(limits.MaxTotalBytes))
+		}
+	}
+	return nil
+}
+
+// remainingBytes returns the maximum number of bytes that the next entry can have
+// without violating the limits, or -1 if it's unlimited.
+func remainingBytes(limits ArchiveLimits, total int64) int64 {
+	max := int64(-1)
+	if limits.MaxFileBytes > 0 {
+		max = limits.MaxFileBytes
+	}
+	if limits.MaxTotalBytes > 0 && (max < 0 || limits.MaxTotalBytes-total < max) {
+		max = limits.MaxTotalBytes - total
+	}
+	return max
+}
+
+// archiveEntryPath returns the cleaned path of the entry relative to the archive's
+// root. It fails if the path points outside the archive.
+func archiveEntryPath(name string) (string, error) {
+	// Older command line clients prefix the paths with a slash.
+	name = strings.TrimPrefix(name, "/")
+	if strings.HasPrefix(name, "/") || strings.Contains(name, "\\") || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("invalid path %q in the archive", name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("path %q points outside the archive", name)
+		}
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." {
+		return "", nil
+	}
+	return cleaned, nil
+}
+
+// archiveSymlinks returns the paths of the archive's symlinks. It fails if the
+// archive has symlinks and they aren't allowed.
+func archiveSymlinks(zr *zip.Reader, limits ArchiveLimits) (map[string]bool, error) {
+	symlinks := make(map[string]bool)
+	for _, f := range zr.File {
+		if f.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		name, err := archiveEntryPath(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !limits.AllowSymlinks {
+			return nil, fmt.Errorf("%v is a symlink, symlinks are not allowed", name)
+		}
+		if symlinks[name] {
+			return nil, fmt.Errorf("symlink %v appears more than once in the archive", name)
+		}
+		symlinks[name] = true
+	}
+	return symlinks, nil
+}
+
+// symlinkParent returns the symlink that one of the path's parent dirs is, or an
+// empty string if none of them is a symlink.
+func symlinkParent(name string, symlinks map[string]bool) string {
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if symlinks[dir] {
+			return dir
+		}
+	}
+	return ""
+}
+
+// checkSymlink checks that the symlink is relative and points inside the archive.
+// As the archive's symlinks can point anywhere inside it, the target must not pass
+// through another symlink of the archive, otherwise its text doesn't tell where it
+// points to.
+func checkSymlink(f *zip.File, name string, symlinks map[string]bool) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %v", name, err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(io.LimitReader(rc, maxSymlinkTarget+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %v", name, err)
+	}
+	if len(b) > maxSymlinkTarget {
+		return fmt.Errorf("the target of symlink %v is too long", name)
+	}
+	target := string(b)
+	if path.IsAbs(target) {
+		return fmt.Errorf("symlink %v points to the absolute path %v", name, target)
+	}
+	cur := path.Dir(name)
+	for _, part := range strings.Split(target, "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		if symlinks[cur] {
+			return fmt.Errorf("symlink %v points through the symlink %v", name, cur)
+		}
+		if part != ".." {
+			cur = path.Join(cur, part)
+			continue
+		}
+		if cur == "." {
+			return fmt.Errorf("symlink %v points outside the archive", name)
+		}
+		cur = path.Dir(cur)
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// formatBytes formats the size in a human readable form (e.g. 1.5MB).
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%vB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,296 @@
+package godge
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testEntry struct {
+	name string
+	body string
+	mode os.FileMode
+}
+
+// buildArchive zips the entries, which are regular files unless they have a mode.
+func buildArchive(t *testing.T, entries ...testEntry) []byte {
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for _, e := range entries {
+		h := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		if e.mode != 0 {
+			h.SetMode(e.mode)
+		}
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			t.Fatalf("failed to create %v: %v", e.name, err)
+		}
+		if _, err := w.Write([]byte(e.body)); err != nil {
+			t.Fatalf("failed to write %v: %v", e.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestArchiveEntryPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "main.go", want: "main.go"},
+		{name: "pkg/util.go", want: "pkg/util.go"},
+		{name: "pkg/", want: "pkg"},
+		{name: "./pkg/./util.go", want: "pkg/util.go"},
+		// Older clients prefix the paths with a slash.
+		{name: "/main.go", want: "main.go"},
+		{name: "/", want: ""},
+		{name: "../main.go", wantErr: true},
+		{name: "pkg/../../main.go", wantErr: true},
+		{name: "pkg/../main.go", wantErr: true},
+		{name: "/../etc/passwd", wantErr: true},
+		{name: "//etc/passwd", wantErr: true},
+		{name: `..\main.go`, wantErr: true},
+		{name: `C:\main.go`, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := archiveEntryPath(test.name)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("archiveEntryPath(%q) = %q, want an error", test.name, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("archiveEntryPath(%q) = %q, %v, want %q", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestValidateArchiveSymlinks(t *testing.T) {
+	allowed := ArchiveLimits{AllowSymlinks: true}
+	tests := []struct {
+		desc    string
+		entries []testEntry
+		limits  ArchiveLimits
+		wantErr string
+	}{
+		{
+			desc:    "symlinks are not allowed",
+			entries: []testEntry{{name: "link", body: "main.go", mode: os.ModeSymlink | 0777}},
+			wantErr: "symlinks are not allowed",
+		},
+		{
+			desc:    "relative symlink inside the archive",
+			entries: []testEntry{{name: "pkg/link", body: "../main.go", mode: os.ModeSymlink | 0777}},
+			limits:  allowed,
+		},
+		{
+			desc:    "relative symlink escaping the archive",
+			entries: []testEntry{{name: "pkg/link", body: "../../etc/passwd", mode: os.ModeSymlink | 0777}},
+			limits:  allowed,
+			wantErr: "points outside the archive",
+		},
+		{
+			desc:    "symlink to the parent of the root",
+			entries: []testEntry{{name: "link", body: "..", mode: os.ModeSymlink | 0777}},
+			limits:  allowed,
+			wantErr: "points outside the archive",
+		},
+		{
+			desc:    "absolute symlink",
+			entries: []testEntry{{name: "link", body: "/etc/passwd", mode: os.ModeSymlink | 0777}},
+			limits:  allowed,
+			wantErr: "absolute path",
+		},
+		{
+			desc: "entries inside a symlinked dir",
+			entries: []testEntry{
+				{name: "s", body: ".", mode: os.ModeSymlink | 0777},
+				{name: "s/x", body: "..", mode: os.ModeSymlink | 0777},
+				{name: "s/x/evil.txt", body: "x"},
+			},
+			limits:  allowed,
+			wantErr: "s/x is inside the symlink s",
+		},
+		{
+			desc: "symlink through a symlink",
+			entries: []testEntry{
+				{name: "x", body: "s/..", mode: os.ModeSymlink | 0777},
+				{name: "s", body: ".", mode: os.ModeSymlink | 0777},
+			},
+			limits:  allowed,
+			wantErr: "points through the symlink s",
+		},
+		{
+			desc: "symlink to a symlink",
+			entries: []testEntry{
+				{name: "a", body: "b", mode: os.ModeSymlink | 0777},
+				{name: "b", body: "main.go", mode: os.ModeSymlink | 0777},
+				{name: "c", body: "b/", mode: os.ModeSymlink | 0777},
+			},
+			limits: allowed,
+		},
+		{
+			desc: "file overwriting a symlink",
+			entries: []testEntry{
+				{name: "link", body: "main.go", mode: os.ModeSymlink | 0777},
+				{name: "link", body: "x"},
+			},
+			limits:  allowed,
+			wantErr: "both a symlink and a file",
+		},
+		{
+			desc:    "symlink with a too long target",
+			entries: []testEntry{{name: "link", body: strings.Repeat("a", maxSymlinkTarget+1), mode: os.ModeSymlink | 0777}},
+			limits:  allowed,
+			wantErr: "too long",
+		},
+	}
+	for _, test := range tests {
+		err := validateArchive(buildArchive(t, test.entries...), test.limits)
+		if test.wantErr == "" {
+			if err != nil {
+				t.Errorf("%v: validateArchive() = %v, want nil", test.desc, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%v: validateArchive() = %v, want an error containing %q", test.desc, err, test.wantErr)
+		}
+	}
+}
+
+func TestValidateArchiveLimits(t *testing.T) {
+	tests := []struct {
+		desc    string
+		entries []testEntry
+		limits  ArchiveLimits
+		wantErr string
+	}{
+		{
+			desc:    "within the limits",
+			entries: []testEntry{{name: "a", body: "12345"}, {name: "b", body: "12345"}},
+			limits:  ArchiveLimits{MaxTotalBytes: 10, MaxFileBytes: 5, MaxEntries: 2},
+		},
+		{
+			desc:    "file too large",
+			entries: []testEntry{{name: "a", body: "123456"}},
+			limits:  ArchiveLimits{MaxFileBytes: 5},
+			wantErr: "larger than the maximum file size",
+		},
+		{
+			desc:    "archive too large",
+			entries: []testEntry{{name: "a", body: "12345"}, {name: "b", body: "123456"}},
+			limits:  ArchiveLimits{MaxTotalBytes: 10},
+			wantErr: "extracted archive is larger",
+		},
+		{
+			desc:    "too many entries",
+			entries: []testEntry{{name: "a/", mode: os.ModeDir | 0755}, {name: "a/b"}, {name: "a/c"}},
+			limits:  ArchiveLimits{MaxEntries: 2},
+			wantErr: "the archive has 3 entries, the maximum is 2",
+		},
+		{
+			desc:    "zip slip",
+			entries: []testEntry{{name: "../evil", body: "x"}},
+			wantErr: "points outside the archive",
+		},
+	}
+	for _, test := range tests {
+		err := validateArchive(buildArchive(t, test.entries...), test.limits)
+		if test.wantErr == "" {
+			if err != nil {
+				t.Errorf("%v: validateArchive() = %v, want nil", test.desc, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%v: validateArchive() = %v, want an error containing %q", test.desc, err, test.wantErr)
+		}
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	root, err := ioutil.TempDir("", "godge-archive-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dir := filepath.Join(root, "sub")
+
+	b := buildArchive(t,
+		testEntry{name: "/main.go", body: "package main"},
+		testEntry{name: "pkg/util.go", body: "package pkg"},
+		testEntry{name: "link", body: "pkg/util.go", mode: os.ModeSymlink | 0777},
+	)
+	if err := extractArchive(b, dir, ArchiveLimits{AllowSymlinks: true}); err != nil {
+		t.Fatalf("extractArchive() = %v, want nil", err)
+	}
+	for name, want := range map[string]string{"main.go": "package main", "pkg/util.go": "package pkg", "link": "package pkg"} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil || string(got) != want {
+			t.Errorf("extracted %v = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	// Nothing is written outside of the dir.
+	b = buildArchive(t, testEntry{name: "../evil", body: "x"})
+	if err := extractArchive(b, dir, ArchiveLimits{}); err == nil {
+		t.Errorf("extractArchive() of a zip slip = nil, want an error")
+	}
+	if _, err := os.Stat(filepath.Join(root, "evil")); !os.IsNotExist(err) {
+		t.Errorf("the zip slip entry was written outside the dir: %v", err)
+	}
+
+	// Nor through a symlinked parent dir.
+	b = buildArchive(t,
+		testEntry{name: "s", body: ".", mode: os.ModeSymlink | 0777},
+		testEntry{name: "s/x", body: "..", mode: os.ModeSymlink | 0777},
+		testEntry{name: "s/x/evil.txt", body: "x"},
+	)
+	if err := extractArchive(b, dir, ArchiveLimits{AllowSymlinks: true}); err == nil {
+		t.Errorf("extractArchive() of a symlinked parent dir = nil, want an error")
+	}
+	if _, err := os.Stat(filepath.Join(root, "evil.txt")); !os.IsNotExist(err) {
+		t.Errorf("the entry was written outside the dir through the symlinks: %v", err)
+	}
+}
+
+// The sizes in the archive's headers must not be trusted.
+func TestExtractArchiveLyingSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "godge-archive-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const maxFileBytes = 1024
+	b := buildArchive(t, testEntry{name: "big", body: strings.Repeat("a", 100*maxFileBytes)})
+	// Claim that the entry is a single byte in the central directory, which is what
+	// the zip reader uses.
+	i := bytes.Index(b, []byte("PK\x01\x02"))
+	if i < 0 {
+		t.Fatal("the archive has no central directory")
+	}
+	binary.LittleEndian.PutUint32(b[i+24:], 1)
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil || zr.File[0].UncompressedSize64 != 1 {
+		t.Fatalf("failed to craft the lying archive: %v", err)
+	}
+
+	if err := extractArchive(b, dir, ArchiveLimits{MaxFileBytes: maxFileBytes}); err == nil {
+		t.Errorf("extractArchive() = nil, want an error")
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "big")); err == nil && fi.Size() > maxFileBytes+1 {
+		t.Errorf("extracted %v bytes of the lying entry, want at most %v", fi.Size(), maxFileBytes+1)
+	}
+}
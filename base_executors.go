@@ -2,24 +2,45 @@ package godge
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
 )
 
-// Executor is used to interact with the submission.
+// Executor is used to interact with the submission. Custom executors can be
+// plugged in using RegisterLanguage.
 type Executor interface {
-	setDockerClient(*docker.Client)
-	containerID() string
-	// Excutes the submitted code with the provided arguments.
+	// Builds the submission (e.g. compiles it or installs its dependencies) and returns
+	// the info of the build. The build happens once per submission: its result is cached
+	// and reused by all the executions, which build the submission first if needed.
+	// Executors with nothing to build return a nil info.
+	Build() (*BuildInfo, error)
+	// Executes the submitted code with the provided arguments.
 	Execute(args []string) error
+	// Executes the submitted code with the provided arguments, writing the input to its
+	// stdin. The stdin is closed after the whole input is written.
+	ExecuteWithInput(args []string, input string) error
+	// Executes the submitted code with the provided arguments, and returns a session
+	// attached to its stdin and stdout for interacting with it.
+	ExecuteInteractive(args []string) (*Session, error)
 	// Reads a certain file from the container's workspace.
 	ReadFileFromContainer(path string) (string, error)
 	// Returns the contents of the stdout of the container.
 	Stdout() (string, error)
 	// Returns the contents for the stderr of the container.
 	Stderr() (string, error)
+	// Returns the address (host:port) where the judge can reach the given port of
+	// the container. The port must be one of the task's Ports.
+	Address(port int) (string, error)
 	// Stops the running binary.
 	Stop() error
 	// A channels that gets signaled when the container starts.
@@ -28,13 +49,78 @@ type Executor interface {
 	DieEvent() chan struct{}
 }
 
+// dockerExecutor is implemented by the executors that run the submissions in
+// docker containers managed by the server (i.e. the ones embedding baseExecutor).
+type dockerExecutor interface {
+	Executor
+	setDockerClient(*docker.Client)
+	setConfig(executorConfig)
+	containerID() string
+	// Marks that the container of the current execution was killed for
+	// exceeding its memory limit.
+	markOOMKilled()
+	// Whether the container of the current execution was killed for exceeding
+	// its memory limit.
+	oomKilled() bool
+	// Marks that the submission failed to compile with the given compiler output.
+	markCompilationError(output string)
+	// The compiler output if the submission failed to compile in the current
+	// execution, and whether it did.
+	compilationError() (string, bool)
+	// The images used by the executor's containers.
+	images() []string
+	// Removes the containers, images and dirs created for the submission.
+	cleanup()
+	// The zip archive of the submitted project.
+	archive() []byte
+}
+
+// executorConfig holds the task specific settings of the executor.
+type executorConfig struct {
+	limits ResourceLimits
+	// The docker network mode of the container running the submission.
+	network string
+	// The docker network mode of the containers building the submission.
+	buildNetwork string
+	// The docker network mode of the containers downloading the submission's
+	// dependencies, and where they're downloaded from.
+	downloadNetwork string
+	goProxy         string
+	pipIndexURL     string
+	// The container ports that the judge needs to reach.
+	ports []int
+	// How to build and run the submissions of the "docker" language.
+	docker *DockerConfig
+	// The image used to build and run the Go submissions.
+	goImage string
+	// The language of the submission, and the pool of idle containers to take the
+	// submission's containers from. The pool is nil if pooling is disabled.
+	language string
+	pool     *containerPool
+	// The id of the submission, used to label its containers and images.
+	submissionID string
+	// The dir under which the submission is unzipped, and the limits of the archive.
+	workDir       string
+	archiveLimits ArchiveLimits
+}
+
 type baseExecutor struct {
 	dockerClient *docker.Client
+	config       executorConfig
 	container    *docker.Container
 	workDir      string
 	stoppedOnce  sync.Once
 	startEvent   chan struct{}
 	dieEvent     chan struct{}
+	oom          int32
+	compileError atomic.Value
+	buildOnce    sync.Once
+	buildInfo    *BuildInfo
+	buildErr     error
+	resources    submissionResources
+	// Whether the containers run an image built for the submission, which no other
+	// submission can use, so they're not taken from the pool.
+	ownImage bool
 }
 
 // init must be called as the first statement for any executor.
@@ -43,6 +129,7 @@ func (b *baseExecutor) init() {
 	b.startEvent = make(chan struct{}, 10)
 	b.dieEvent = make(chan struct{}, 10)
 	b.stoppedOnce = sync.Once{}
+	atomic.StoreInt32(&b.oom, 0)
 }
 
 // StartEvent returns a channel that gets signaled when the container starts.
@@ -66,6 +153,310 @@ func (b *baseExecutor) setDockerClient(d *docker.Client) {
 	b.dockerClient = d
 }
 
+func (b *baseExecutor) setConfig(c executorConfig) {
+	b.config = c
+}
+
+func (b *baseExecutor) markOOMKilled() {
+	atomic.StoreInt32(&b.oom, 1)
+}
+
+func (b *baseExecutor) oomKilled() bool {
+	return atomic.LoadInt32(&b.oom) == 1
+}
+
+// compilationResult is stored in baseExecutor.compileError.
+type compilationResult struct {
+	failed bool
+	output string
+}
+
+func (b *baseExecutor) markCompilationError(output string) {
+	b.compileError.Store(compilationResult{failed: true, output: output})
+}
+
+func (b *baseExecutor) compilationError() (string, bool) {
+	r, _ := b.compileError.Load().(compilationResult)
+	return r.output, r.failed
+}
+
+// BuildInfo describes the build of a submission.
+type BuildInfo struct {
+	// The output of the build (e.g. the compiler's warnings and errors).
+	Output string `json:"output,omitempty"`
+	// How long the build took.
+	Duration time.Duration `json:"duration"`
+}
+
+// buildFunc builds the submission and returns the output of the build.
+type buildFunc func() (string, error)
+
+// build runs the build func on the first call, and returns its cached result on
+// the following ones.
+func (b *baseExecutor) build(f buildFunc) (*BuildInfo, error) {
+	b.buildOnce.Do(func() {
+		if b.dockerClient == nil {
+			// Panic if there's a logic error
+			panic("Docker client must be set for the executor")
+		}
+		start := time.Now()
+		output, err := f()
+		b.buildInfo = &BuildInfo{
+			Output:   truncateOutput(output),
+			Duration: time.Since(start),
+		}
+		b.buildErr = err
+	})
+	return b.buildInfo, b.buildErr
+}
+
+// createContainer takes the container from the pool if possible, and creates it
+// otherwise. The container is removed when the submission is cleaned up.
+func (b *baseExecutor) createContainer(option docker.CreateContainerOptions) (*docker.Container, error) {
+	option.Config.Labels = b.labels()
+	c, ok := (*docker.Container)(nil), false
+	if b.config.pool != nil && !b.ownImage {
+		c, ok = b.config.pool.take(b.config.language, option)
+	}
+	if !ok {
+		var err error
+		if c, err = b.dockerClient.CreateContainer(option); err != nil {
+			return nil, err
+		}
+	}
+	b.resources.addContainer(c.ID)
+	return c, nil
+}
+
+// labels returns the labels of the submission's containers and images.
+func (b *baseExecutor) labels() map[string]string {
+	return map[string]string{
+		labelGodge:      "true",
+		labelSubmission: b.config.submissionID,
+	}
+}
+
+// unzip unzips the archive into a new dir that's removed when the submission is
+// cleaned up.
+func (b *baseExecutor) unzip(archive []byte) (string, error) {
+	root := b.config.workDir
+	if root == "" {
+		root = defaultWorkDir
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", fmt.Errorf("failed to create work dir: %v", err)
+	}
+	dir, err := unzipToTmpDir(archive, root, b.config.archiveLimits)
+	if err != nil {
+		return "", err
+	}
+	b.resources.addDir(dir)
+	return dir, nil
+}
+
+func (b *baseExecutor) cleanup() {
+	if b.config.pool != nil {
+		// The pooled containers of the removed images would never be used.
+		b.resources.Lock()
+		images := append([]string(nil), b.resources.images...)
+		b.resources.Unlock()
+		for _, image := range images {
+			b.config.pool.evict(image)
+		}
+	}
+	b.resources.remove(b.dockerClient)
+}
+
+// hostConfig returns the host config of the executor's containers with the task's
+// resource limits applied.
+func (b *baseExecutor) hostConfig(binds []string, networkMode string) *docker.HostConfig {
+	hc := &docker.HostConfig{
+		Binds:       binds,
+		NetworkMode: networkMode,
+	}
+	b.config.limits.apply(hc)
+	return hc
+}
+
+// prepareFunc prepares the execution of the submission with the given arguments
+// (e.g. by building it), and returns the options of the container that runs it.
+type prepareFunc func(args []string) (docker.CreateContainerOptions, error)
+
+// execute runs the container prepared by the executor.
+func (b *baseExecutor) execute(prepare prepareFunc, args []string) error {
+	_, err := b.run(prepare, args, nil, nil)
+	return err
+}
+
+// run runs the container prepared by the executor. If stdin or stdout are not nil,
+// they're attached to the stdin and stdout of the container, and the returned waiter
+// can be used to wait for the streams to end.
+func (b *baseExecutor) run(prepare prepareFunc, args []string, stdin io.Reader, stdout io.Writer) (docker.CloseWaiter, error) {
+	b.init()
+	if b.dockerClient == nil {
+		// Panic if there's a logic error
+		panic("Docker client must be set for the executor")
+	}
+
+	option, err := prepare(args)
+	if err != nil {
+		return nil, err
+	}
+	if stdin != nil {
+		option.Config.OpenStdin = true
+		option.Config.StdinOnce = true
+		option.Config.AttachStdin = true
+	}
+
+	b.container, err = b.createContainer(option)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %v", err)
+	}
+
+	var waiter docker.CloseWaiter
+	if stdin != nil || stdout != nil {
+		// The streams must be attached before starting the container to not miss anything.
+		success := make(chan struct{})
+		waiter, err = b.dockerClient.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
+			Container:    b.container.ID,
+			InputStream:  stdin,
+			OutputStream: stdout,
+			Stdin:        stdin != nil,
+			Stdout:       stdout != nil,
+			Stream:       true,
+			Success:      success,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to attach to container: %v", err)
+		}
+		<-success
+		success <- struct{}{}
+	}
+
+	if err := b.dockerClient.StartContainer(b.container.ID, nil); err != nil {
+		return nil, fmt.Errorf("failed to start container: %v", err)
+	}
+	return waiter, nil
+}
+
+// executeWithInput runs the container prepared by the executor with the input
+// written to its stdin.
+func (b *baseExecutor) executeWithInput(prepare prepareFunc, args []string, input string) error {
+	_, err := b.run(prepare, args, strings.NewReader(input), nil)
+	return err
+}
+
+// executeInteractive runs the container prepared by the executor and returns a
+// session attached to its stdin and stdout.
+func (b *baseExecutor) executeInteractive(prepare prepareFunc, args []string) (*Session, error) {
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	waiter, err := b.run(prepare, args, inr, outw)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		// The attached streams end when the container dies.
+		waiter.Wait()
+		outw.Close()
+	}()
+	return NewSession(inw, outr), nil
+}
+
+// exposePorts exposes the task's ports on the container running the submission. The
+// ports are reached using the container's IP rather than published on the host, as
+// docker's proxy of the published ports accepts the connections before the submission
+// listens on them.
+func (b *baseExecutor) exposePorts(c *docker.Config) {
+	if len(b.config.ports) == 0 {
+		return
+	}
+	c.ExposedPorts = make(map[docker.Port]struct{})
+	for _, p := range b.config.ports {
+		c.ExposedPorts[dockerPort(p)] = struct{}{}
+	}
+}
+
+func dockerPort(p int) docker.Port {
+	return docker.Port(fmt.Sprintf("%v/tcp", p))
+}
+
+// Address returns the address (host:port) where the judge can reach the given port
+// of the running container.
+func (b *baseExecutor) Address(port int) (string, error) {
+	if b.container == nil {
+		return "", fmt.Errorf("the submission is not running")
+	}
+	exposed := false
+	for _, p := range b.config.ports {
+		exposed = exposed || p == port
+	}
+	if !exposed {
+		return "", fmt.Errorf("port %v is not one of the task's ports", port)
+	}
+	if b.config.network == "none" {
+		return "", fmt.Errorf("networking is disabled for this task")
+	}
+	c, err := b.dockerClient.InspectContainer(b.container.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container: %v", err)
+	}
+	if c.NetworkSettings == nil {
+		return "", fmt.Errorf("container has no network settings")
+	}
+	for _, n := range c.NetworkSettings.Networks {
+		if n.IPAddress != "" {
+			return net.JoinHostPort(n.IPAddress, strconv.Itoa(port)), nil
+		}
+	}
+	return "", fmt.Errorf("container has no reachable address")
+}
+
+// runToCompletion runs a helper container (e.g. to build the submission) and waits
+// for it to exit. It returns the combined stdout and stderr of the container and its
+// exit code. The container is killed if it doesn't exit within the timeout, and it's
+// removed afterwards.
+func (b *baseExecutor) runToCompletion(option docker.CreateContainerOptions, timeout time.Duration) (string, int, error) {
+	option.Config.Labels = b.labels()
+	c, err := b.dockerClient.CreateContainer(option)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create container: %v", err)
+	}
+	defer b.dockerClient.RemoveContainer(docker.RemoveContainerOptions{
+		ID:            c.ID,
+		RemoveVolumes: true,
+		Force:         true,
+	})
+	if err := b.dockerClient.StartContainer(c.ID, nil); err != nil {
+		return "", 0, fmt.Errorf("failed to start container: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	code, err := b.dockerClient.WaitContainerWithContext(c.ID, ctx)
+	if err != nil {
+		b.dockerClient.KillContainer(docker.KillContainerOptions{ID: c.ID})
+		if ctx.Err() != nil {
+			return "", 0, fmt.Errorf("container didn't exit within %v", timeout)
+		}
+		return "", 0, fmt.Errorf("failed to wait for container: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	err = b.dockerClient.Logs(docker.LogsOptions{
+		OutputStream: buf,
+		ErrorStream:  buf,
+		Container:    c.ID,
+		Stdout:       true,
+		Stderr:       true,
+		Tail:         "all",
+	})
+	if err != nil {
+		return "", code, fmt.Errorf("failed to read container logs: %v", err)
+	}
+	return buf.String(), code, nil
+}
+
 // ReadFileFromContainer reads a certain file from the container's workspace. The path
 // is relative to the container's workdir.
 func (b *baseExecutor) ReadFileFromContainer(path string) (string, error) {
@@ -112,6 +503,9 @@ func (b *baseExecutor) Stderr() (string, error) {
 
 // Stop stops the running binary.
 func (b *baseExecutor) Stop() error {
+	if b.container == nil {
+		return nil
+	}
 	var err error
 	b.stoppedOnce.Do(func() {
 		if err = b.dockerClient.StopContainer(b.container.ID, 2); err != nil {
@@ -0,0 +1,131 @@
+package godge
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// The labels of the containers and images created by the judge.
+const (
+	// labelGodge marks all the containers and images created by the judge.
+	labelGodge = "godge"
+	// labelSubmission holds the id of the submission that created the container or image.
+	labelSubmission = "godge.submission"
+)
+
+// The prefix of the dirs the submissions are unzipped into.
+const workDirPrefix = "godge"
+
+// defaultWorkDir is the default root of the submissions' dirs.
+var defaultWorkDir = filepath.Join(os.TempDir(), "godge")
+
+// submissionResources tracks the docker resources and the dirs created for a
+// submission, to be removed once it's judged.
+type submissionResources struct {
+	sync.Mutex
+	containers []string
+	images     []string
+	dirs       []string
+}
+
+func (r *submissionResources) addContainer(id string) {
+	r.Lock()
+	defer r.Unlock()
+	r.containers = append(r.containers, id)
+}
+
+func (r *submissionResources) addImage(name string) {
+	r.Lock()
+	defer r.Unlock()
+	r.images = append(r.images, name)
+}
+
+func (r *submissionResources) addDir(dir string) {
+	r.Lock()
+	defer r.Unlock()
+	r.dirs = append(r.dirs, dir)
+}
+
+// remove removes all the tracked resources. Failures are logged, as there's nothing
+// else to do about them.
+func (r *submissionResources) remove(dc *docker.Client) {
+	r.Lock()
+	defer r.Unlock()
+	for _, id := range r.containers {
+		err := dc.RemoveContainer(docker.RemoveContainerOptions{
+			ID:            id,
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		if _, ok := err.(*docker.NoSuchContainer); err != nil && !ok {
+			log.Printf("failed to remove container %v: %v", id, err)
+		}
+	}
+	for _, image := range r.images {
+		if err := dc.RemoveImageExtended(image, docker.RemoveImageOptions{Force: true}); err != nil && err != docker.ErrNoSuchImage {
+			log.Printf("failed to remove image %v: %v", image, err)
+		}
+	}
+	for _, dir := range r.dirs {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("failed to remove dir %v: %v", dir, err)
+		}
+	}
+	r.containers, r.images, r.dirs = nil, nil, nil
+}
+
+// sweepOrphans removes the containers, images and dirs left behind by a previous
+// run of the judge (e.g. if it crashed while judging submissions).
+func sweepOrphans(dc *docker.Client, workDir string) error {
+	filters := map[string][]string{"label": {labelGodge}}
+	containers, err := dc.ListContainers(docker.ListContainersOptions{All: true, Filters: filters})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %v", err)
+	}
+	for _, c := range containers {
+		err := dc.RemoveContainer(docker.RemoveContainerOptions{
+			ID:            c.ID,
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove container %v: %v", c.ID, err)
+		}
+	}
+	images, err := dc.ListImages(docker.ListImagesOptions{Filters: filters})
+	if err != nil {
+		return fmt.Errorf("failed to list images: %v", err)
+	}
+	for _, image := range images {
+		if err := dc.RemoveImageExtended(image.ID, docker.RemoveImageOptions{Force: true}); err != nil && err != docker.ErrNoSuchImage {
+			return fmt.Errorf("failed to remove image %v: %v", image.ID, err)
+		}
+	}
+	if len(containers)+len(images) > 0 {
+		log.Printf("Removed %v orphan containers and %v orphan images", len(containers), len(images))
+	}
+
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work dir: %v", err)
+	}
+	entries, err := ioutil.ReadDir(workDir)
+	if err != nil {
+		return fmt.Errorf("failed to read work dir: %v", err)
+	}
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), workDirPrefix) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(workDir, e.Name())); err != nil {
+			return fmt.Errorf("failed to remove dir %v: %v", e.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/MohamedBassem/godge"
+	"github.com/google/subcommands"
+)
+
+type contestsCmd struct {
+}
+
+func (*contestsCmd) Name() string     { return "contests" }
+func (*contestsCmd) Synopsis() string { return "Prints the contests and their description." }
+func (*contestsCmd) Usage() string {
+	return `contests:
+  Prints the contests of the server, which are selected using the -contest flag.
+`
+}
+
+func (c *contestsCmd) SetFlags(f *flag.FlagSet) {
+}
+
+func (c *contestsCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if *serverAddress == "" {
+		log.Fatal("Server Address must be specified")
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%v/contests", *serverAddress))
+	if err != nil {
+		log.Printf("Failed to fetch contests: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer resp.Body.Close()
+	if err := checkResponseError(resp); err != nil {
+		log.Printf("Fetching contests failed: %v", err)
+		return subcommands.ExitFailure
+	}
+
+	var cs []godge.Contest
+	if err := json.NewDecoder(resp.Body).Decode(&cs); err != nil {
+		log.Printf("Decoding response failed: %v", err)
+		return subcommands.ExitFailure
+	}
+	if len(cs) == 0 {
+		log.Println("The server has no contests other than the default one")
+		return subcommands.ExitSuccess
+	}
+
+	for _, c := range cs {
+		fmt.Printf("%v: %v\n", c.Name, c.Desc)
+		fmt.Printf("Starts: %v\n", c.Start.Local().Format(time.RFC1123))
+		if !c.End.IsZero() {
+			fmt.Printf("Ends: %v\n", c.End.Local().Format(time.RFC1123))
+		}
+		fmt.Println("=============================")
+	}
+
+	return subcommands.ExitSuccess
+}
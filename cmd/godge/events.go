@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MohamedBassem/godge"
+)
+
+// streamSubmission follows the events stream of the submission and prints its
+// progress as it happens. It returns the result of the submission once it's done.
+func streamSubmission(username, password, id string) (*godge.SubmissionResponse, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%v/submissions/%v/events", *serverAddress, id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.SetBasicAuth(username, password)
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream stays open as long as the submission is being judged.
+	client := &http.Client{
+		Timeout: 0,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to follow submission: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponseError(resp); err != nil {
+		return nil, fmt.Errorf("following submission failed: %v", err)
+	}
+
+	lastPosition := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			// Event names, keep-alive comments and the blank separators.
+			continue
+		}
+		var ev godge.SubmissionEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &ev); err != nil {
+			return nil, fmt.Errorf("failed to decode event: %v", err)
+		}
+		switch ev.Type {
+		case godge.EventQueued:
+			if ev.QueuePosition != lastPosition {
+				log.Printf("Position in queue: %v", ev.QueuePosition)
+				lastPosition = ev.QueuePosition
+			}
+		case godge.EventRunning:
+			log.Println("Running ..")
+		case godge.EventBuilding:
+			log.Println("Building ..")
+		case godge.EventBuilt:
+			if ev.Error != "" {
+				log.Printf("Build failed%v", buildDuration(ev.Build))
+			} else {
+				log.Printf("Built%v", buildDuration(ev.Build))
+			}
+		case godge.EventTestStarted:
+			log.Printf("  RUN  %v", ev.Test)
+		case godge.EventTestPassed:
+			log.Printf("  PASS %v%v", ev.Test, testDuration(ev.TestResult))
+		case godge.EventTestFailed:
+			log.Printf("  FAIL %v%v: %v", ev.Test, testDuration(ev.TestResult), firstLine(ev.Error))
+		case godge.EventDone:
+			if ev.Result == nil {
+				return nil, fmt.Errorf("submission %v finished without a result", id)
+			}
+			return ev.Result, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("lost the submission's stream: %v", err)
+	}
+	return nil, fmt.Errorf("lost the submission's stream")
+}
+
+func buildDuration(b *godge.BuildInfo) string {
+	if b == nil {
+		return ""
+	}
+	return fmt.Sprintf(" in %v", b.Duration.Round(time.Millisecond))
+}
+
+func testDuration(r *godge.TestResult) string {
+	if r == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (%v)", r.Duration.Round(time.Millisecond))
+}
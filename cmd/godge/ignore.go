@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// The name of the file listing the paths that shouldn't be submitted.
+const godgeIgnoreFile = ".godgeignore"
+
+// defaultIgnorePatterns are excluded from every submission. They can be re-included
+// with negated patterns in .godgeignore.
+var defaultIgnorePatterns = []string{
+	".git/",
+	".hg/",
+	".svn/",
+	".DS_Store",
+	"*.swp",
+	"*.swo",
+	"*~",
+}
+
+// ignoreRule is a single pattern of an ignore file, in gitignore syntax.
+type ignoreRule struct {
+	// The dir of the ignore file that the rule came from, relative to the root of the
+	// submission ("" for the root).
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreRules decides which paths are excluded from the submission. Later rules
+// take precedence over earlier ones, as in gitignore.
+type ignoreRules []ignoreRule
+
+// parseIgnorePattern parses a single line of an ignore file. It returns false if the
+// line is blank or a comment.
+func parseIgnorePattern(base, line string) (ignoreRule, bool, error) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false, nil
+	}
+	r := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		// Escaped "#" or "!".
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false, nil
+	}
+	// Patterns with a slash (other than a trailing one) are relative to the ignore
+	// file's dir, the others match at any depth.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegexp(line)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return ignoreRule{}, false, fmt.Errorf("invalid pattern %q: %v", line, err)
+	}
+	r.re = re
+	return r, true, nil
+}
+
+// globToRegexp converts a gitignore glob to a regular expression.
+func globToRegexp(glob string) string {
+	var b bytes.Buffer
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// add parses the patterns and appends them to the rules.
+func (rs *ignoreRules) add(base string, patterns []string) error {
+	for _, p := range patterns {
+		r, ok, err := parseIgnorePattern(base, p)
+		if err != nil {
+			return err
+		}
+		if ok {
+			*rs = append(*rs, r)
+		}
+	}
+	return nil
+}
+
+// addFile appends the rules of the ignore file, if it exists. base is the dir of the
+// file relative to the root of the submission.
+func (rs *ignoreRules) addFile(file, base string) error {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %v: %v", file, err)
+	}
+	defer f.Close()
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %v: %v", file, err)
+	}
+	if err := rs.add(base, patterns); err != nil {
+		return fmt.Errorf("%v: %v", file, err)
+	}
+	return nil
+}
+
+// ignored returns whether the path (slash separated and relative to the root of the
+// submission) is excluded.
+func (rs ignoreRules) ignored(name string, isDir bool) bool {
+	ret := false
+	for _, r := range rs {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel := name
+		if r.base != "" {
+			if !strings.HasPrefix(name, r.base+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(name, r.base+"/")
+		}
+		if r.re.MatchString(rel) {
+			ret = !r.negate
+		}
+	}
+	return ret
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+type ignoreCheck struct {
+	name  string
+	isDir bool
+	want  bool
+}
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		desc string
+		// The patterns of the ignore files by their dir, added after the defaults.
+		files  []struct{ base, patterns string }
+		checks []ignoreCheck
+	}{
+		{
+			desc: "default exclusions",
+			checks: []ignoreCheck{
+				{name: ".git", isDir: true, want: true},
+				{name: "pkg/.hg", isDir: true, want: true},
+				{name: ".svn", isDir: true, want: true},
+				// The dir patterns don't match files.
+				{name: ".git", want: false},
+				{name: ".DS_Store", want: true},
+				{name: "pkg/.DS_Store", want: true},
+				{name: "main.go.swp", want: true},
+				{name: "pkg/util.go.swo", want: true},
+				{name: "main.go~", want: true},
+				{name: "main.go", want: false},
+				{name: "pkg", isDir: true, want: false},
+			},
+		},
+		{
+			desc:  "re-included default exclusions",
+			files: []struct{ base, patterns string }{{"", "!.DS_Store\n!.git/"}},
+			checks: []ignoreCheck{
+				{name: ".DS_Store", want: false},
+				{name: "pkg/.DS_Store", want: false},
+				{name: ".git", isDir: true, want: false},
+				{name: ".hg", isDir: true, want: true},
+				{name: "main.go.swp", want: true},
+			},
+		},
+		{
+			desc:  "patterns without a slash match at any depth",
+			files: []struct{ base, patterns string }{{"", "*.log"}},
+			checks: []ignoreCheck{
+				{name: "a.log", want: true},
+				{name: "pkg/sub/a.log", want: true},
+				{name: "logs", isDir: true, want: false},
+				{name: "a.logx", want: false},
+			},
+		},
+		{
+			desc:  "patterns with a slash are anchored",
+			files: []struct{ base, patterns string }{{"", "/build\ndocs/*.md"}},
+			checks: []ignoreCheck{
+				{name: "build", isDir: true, want: true},
+				{name: "build", want: true},
+				{name: "pkg/build", isDir: true, want: false},
+				{name: "docs/a.md", want: true},
+				{name: "docs/sub/a.md", want: false},
+				{name: "pkg/docs/a.md", want: false},
+			},
+		},
+		{
+			desc:  "double stars",
+			files: []struct{ base, patterns string }{{"", "**/tmp\nlogs/**\na/**/b"}},
+			checks: []ignoreCheck{
+				{name: "tmp", isDir: true, want: true},
+				{name: "x/y/tmp", isDir: true, want: true},
+				{name: "logs/a", want: true},
+				{name: "logs/a/b", want: true},
+				{name: "logs", isDir: true, want: false},
+				{name: "a/b", want: true},
+				{name: "a/x/y/b", want: true},
+				{name: "a/xb", want: false},
+			},
+		},
+		{
+			desc:  "dir only patterns",
+			files: []struct{ base, patterns string }{{"", "out/"}},
+			checks: []ignoreCheck{
+				{name: "out", isDir: true, want: true},
+				{name: "pkg/out", isDir: true, want: true},
+				{name: "out", want: false},
+			},
+		},
+		{
+			desc:  "wildcards and classes",
+			files: []struct{ base, patterns string }{{"", "file?.txt\n[ab].go\n[!xy].c"}},
+			checks: []ignoreCheck{
+				{name: "file1.txt", want: true},
+				{name: "file10.txt", want: false},
+				{name: "a.go", want: true},
+				{name: "c.go", want: false},
+				{name: "z.c", want: true},
+				{name: "x.c", want: false},
+			},
+		},
+		{
+			desc:  "comments, escapes and blank lines",
+			files: []struct{ base, patterns string }{{"", "# main.go\n\n\\#notes\n\\!important\nsecret.txt  \n"}},
+			checks: []ignoreCheck{
+				{name: "main.go", want: false},
+				{name: "#notes", want: true},
+				{name: "!important", want: true},
+				{name: "secret.txt", want: true},
+			},
+		},
+		{
+			desc:  "negation",
+			files: []struct{ base, patterns string }{{"", "*.txt\n!keep.txt\n!drop.txt\ndrop.txt"}},
+			checks: []ignoreCheck{
+				{name: "notes.txt", want: true},
+				{name: "keep.txt", want: false},
+				{name: "pkg/keep.txt", want: false},
+				// Later rules take precedence.
+				{name: "drop.txt", want: true},
+			},
+		},
+		{
+			desc: "ignore files of subdirs",
+			files: []struct{ base, patterns string }{
+				{"", "*.tmp"},
+				{"pkg", "!keep.tmp\n/gen\ncache/"},
+			},
+			checks: []ignoreCheck{
+				{name: "a.tmp", want: true},
+				{name: "pkg/a.tmp", want: true},
+				{name: "pkg/keep.tmp", want: false},
+				{name: "keep.tmp", want: true},
+				{name: "pkg/gen", want: true},
+				{name: "pkg/sub/gen", want: false},
+				{name: "gen", want: false},
+				{name: "pkg/sub/cache", isDir: true, want: true},
+				{name: "cache", isDir: true, want: false},
+				{name: "pkgx/gen", want: false},
+			},
+		},
+	}
+	for _, test := range tests {
+		var rules ignoreRules
+		if err := rules.add("", defaultIgnorePatterns); err != nil {
+			t.Fatalf("%v: failed to add the default patterns: %v", test.desc, err)
+		}
+		for _, f := range test.files {
+			if err := rules.add(f.base, strings.Split(f.patterns, "\n")); err != nil {
+				t.Fatalf("%v: failed to add patterns: %v", test.desc, err)
+			}
+		}
+		for _, c := range test.checks {
+			if got := rules.ignored(c.name, c.isDir); got != c.want {
+				t.Errorf("%v: ignored(%q, %v) = %v, want %v", test.desc, c.name, c.isDir, got, c.want)
+			}
+		}
+	}
+}
+
+func TestIgnoreRulesInvalidPattern(t *testing.T) {
+	var rules ignoreRules
+	if err := rules.add("", []string{"[z-a].go"}); err == nil {
+		t.Error("add() of an invalid class = nil, want an error")
+	}
+}
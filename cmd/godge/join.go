@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/google/subcommands"
+)
+
+type joinCmd struct {
+	username string
+	password string
+}
+
+func (*joinCmd) Name() string     { return "join" }
+func (*joinCmd) Synopsis() string { return "Joins a contest." }
+func (*joinCmd) Usage() string {
+	return `-contest <contest> join -username <username> -password <password>:
+  Joins the contest, which is required before submitting to its tasks.
+`
+}
+
+func (j *joinCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&j.username, "username", os.Getenv("GODGE_USERNAME"), "Your username")
+	f.StringVar(&j.password, "password", os.Getenv("GODGE_PASSWORD"), "Your password")
+}
+
+func (j *joinCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if *contestName == "" {
+		log.Println("Contest must be specified")
+		return subcommands.ExitUsageError
+	}
+	if j.username == "" {
+		log.Println("Username must be specified")
+		return subcommands.ExitUsageError
+	}
+	if j.password == "" {
+		log.Println("Password must be specified")
+		return subcommands.ExitUsageError
+	}
+	if *serverAddress == "" {
+		log.Fatal("Server Address must be specified")
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%v/join%v", *serverAddress, contestQuery()), nil)
+	if err != nil {
+		log.Printf("Failed to create request: %v", err)
+		return subcommands.ExitFailure
+	}
+	req.SetBasicAuth(j.username, j.password)
+
+	client := &http.Client{
+		Timeout: requestTimeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Failed to join contest: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer resp.Body.Close()
+	if err := checkResponseError(resp); err != nil {
+		log.Printf("Joining contest failed: %v", err)
+		return subcommands.ExitFailure
+	}
+
+	log.Printf("Joined contest %v", *contestName)
+	return subcommands.ExitSuccess
+}
@@ -8,15 +8,24 @@ import (
 	"github.com/google/subcommands"
 )
 
-var serverAddress = flag.String("address", os.Getenv("GODGE_ADDR"), "The address of the server")
+var (
+	serverAddress = flag.String("address", os.Getenv("GODGE_ADDR"), "The address of the server")
+	contestName   = flag.String("contest", os.Getenv("GODGE_CONTEST"), "The contest to use, the server's default one if empty")
+)
 
 func main() {
 	subcommands.ImportantFlag("address")
+	subcommands.ImportantFlag("contest")
 	subcommands.Register(subcommands.FlagsCommand(), "")
 	subcommands.Register(subcommands.CommandsCommand(), "")
 	subcommands.Register(&submitCmd{}, "")
 	subcommands.Register(&registerCmd{}, "")
 	subcommands.Register(&tasksCmd{}, "")
+	subcommands.Register(&contestsCmd{}, "")
+	subcommands.Register(&joinCmd{}, "")
+	subcommands.Register(&statusCmd{}, "")
+	subcommands.Register(&selfCheckCmd{}, "")
+	subcommands.Register(&unfreezeCmd{}, "")
 	flag.Parse()
 
 	ctx := context.Background()
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/MohamedBassem/godge"
+	"github.com/google/subcommands"
+)
+
+type selfCheckCmd struct {
+	token string
+	tasks string
+}
+
+func (*selfCheckCmd) Name() string     { return "selfcheck" }
+func (*selfCheckCmd) Synopsis() string { return "Checks the tasks using their solutions." }
+func (*selfCheckCmd) Usage() string {
+	return `selfcheck -token <adminToken> [-task <task1,task2>]:
+  Runs the reference and known bad solutions of the contest's tasks (all of them by default)
+  through the server's executors, and reports the solutions that don't behave as expected.
+`
+}
+
+func (s *selfCheckCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&s.token, "token", os.Getenv("GODGE_ADMIN_TOKEN"), "The server's admin token")
+	f.StringVar(&s.tasks, "task", "", "Comma separated tasks to check")
+}
+
+func (s *selfCheckCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if s.token == "" {
+		log.Println("Admin token must be specified")
+		return subcommands.ExitUsageError
+	}
+	if *serverAddress == "" {
+		log.Fatal("Server Address must be specified")
+	}
+
+	q := url.Values{}
+	if *contestName != "" {
+		q.Set("contest", *contestName)
+	}
+	for _, t := range strings.Split(s.tasks, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			q.Add("task", t)
+		}
+	}
+	req, err := http.NewRequest("POST", fmt.Sprintf("%v/admin/selfcheck?%v", *serverAddress, q.Encode()), nil)
+	if err != nil {
+		log.Printf("Failed to create request: %v", err)
+		return subcommands.ExitFailure
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+
+	log.Println("Running the solutions, this might take a while ..")
+	// No timeout, as the solutions are judged while the request is open.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Printf("Failed to run the self-check: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer resp.Body.Close()
+	if err := checkResponseError(resp); err != nil {
+		log.Printf("Self-check failed: %v", err)
+		return subcommands.ExitFailure
+	}
+
+	var checks []godge.SolutionCheck
+	if err := json.NewDecoder(resp.Body).Decode(&checks); err != nil {
+		log.Printf("Decoding response failed: %v", err)
+		return subcommands.ExitFailure
+	}
+	if len(checks) == 0 {
+		log.Println("The tasks have no solutions to check")
+		return subcommands.ExitSuccess
+	}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TASK\tSOLUTION\tKIND\tRESULT\tERROR")
+	for _, c := range checks {
+		kind := "bad"
+		if c.Reference {
+			kind = "reference"
+		}
+		result := "OK"
+		if !c.OK {
+			result = "FAILED"
+			failed++
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", c.Task, c.Solution, kind, result, firstLine(c.Error))
+	}
+	w.Flush()
+
+	if failed > 0 {
+		log.Printf("%v of %v solutions didn't behave as expected", failed, len(checks))
+		return subcommands.ExitFailure
+	}
+	log.Println("All the solutions behaved as expected")
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/MohamedBassem/godge"
+	"github.com/google/subcommands"
+)
+
+const (
+	// The timeout of a single request to the server.
+	requestTimeout = 30 * time.Second
+	// How often the status of a pending submission is polled.
+	pollInterval = time.Second
+	// The number of consecutive failed polls before giving up.
+	maxPollFailures = 30
+)
+
+type statusCmd struct {
+	id       string
+	username string
+	password string
+	wait     bool
+}
+
+func (*statusCmd) Name() string     { return "status" }
+func (*statusCmd) Synopsis() string { return "Prints the status of a submission." }
+func (*statusCmd) Usage() string {
+	return `status -id <submissionID> -username <username> -password <password> [-wait]:
+  Prints the status of a submission. With -wait, it waits until the submission is judged.
+`
+}
+
+func (s *statusCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&s.id, "id", "", "The ID of the submission")
+	f.StringVar(&s.username, "username", os.Getenv("GODGE_USERNAME"), "Your username")
+	f.StringVar(&s.password, "password", os.Getenv("GODGE_PASSWORD"), "Your password")
+	f.BoolVar(&s.wait, "wait", false, "Wait for the submission to be judged")
+}
+
+func (s *statusCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if s.id == "" {
+		log.Println("Submission ID must be specified")
+		return subcommands.ExitUsageError
+	}
+	if s.username == "" {
+		log.Println("Username must be specified")
+		return subcommands.ExitUsageError
+	}
+	if s.password == "" {
+		log.Println("Password must be specified")
+		return subcommands.ExitUsageError
+	}
+	if *serverAddress == "" {
+		log.Fatal("Server Address must be specified")
+	}
+
+	if s.wait {
+		result, err := followSubmission(s.username, s.password, s.id)
+		if err != nil {
+			log.Println(err)
+			return subcommands.ExitFailure
+		}
+		printSubmissionResult(result)
+		return subcommands.ExitSuccess
+	}
+
+	status, err := fetchSubmissionStatus(s.username, s.password, s.id)
+	if err != nil {
+		log.Println(err)
+		return subcommands.ExitFailure
+	}
+
+	if status.Result == nil {
+		log.Printf("Submission %v for %v is %v", status.ID, status.TaskName, status.Status)
+		return subcommands.ExitSuccess
+	}
+	printSubmissionResult(status.Result)
+	return subcommands.ExitSuccess
+}
+
+// networkError wraps the errors that are caused by failing to reach the server.
+type networkError struct {
+	error
+}
+
+func fetchSubmissionStatus(username, password, id string) (*godge.SubmissionStatus, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%v/submissions/%v", *serverAddress, id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.SetBasicAuth(username, password)
+
+	client := &http.Client{
+		Timeout: requestTimeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, networkError{fmt.Errorf("failed to fetch submission status: %v", err)}
+	}
+	defer resp.Body.Close()
+	if err := checkResponseError(resp); err != nil {
+		return nil, fmt.Errorf("fetching submission status failed: %v", err)
+	}
+
+	var status godge.SubmissionStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %v", err)
+	}
+	return &status, nil
+}
+
+// waitForSubmission polls the status of the submission until it's done. Network
+// errors are retried, so that a flaky connection doesn't lose the verdict.
+func waitForSubmission(username, password, id string) (*godge.SubmissionStatus, error) {
+	failures := 0
+	lastStatus := ""
+	for {
+		status, err := fetchSubmissionStatus(username, password, id)
+		if _, ok := err.(networkError); ok {
+			failures++
+			if failures >= maxPollFailures {
+				return nil, fmt.Errorf("%v\nYou can reattach later with: godge status -wait -id %v", err, id)
+			}
+			log.Printf("%v, retrying ..", err)
+			time.Sleep(pollInterval)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		failures = 0
+		if status.Status == godge.StatusDone {
+			return status, nil
+		}
+		if status.Status != lastStatus {
+			log.Printf("Submission %v is %v", id, status.Status)
+			lastStatus = status.Status
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// followSubmission renders the live progress of the submission and returns its
+// result. If the stream breaks, it falls back to polling the submission's status.
+func followSubmission(username, password, id string) (*godge.SubmissionResponse, error) {
+	result, err := streamSubmission(username, password, id)
+	if err == nil {
+		return result, nil
+	}
+	log.Printf("%v, polling the submission's status instead ..", err)
+	status, err := waitForSubmission(username, password, id)
+	if err != nil {
+		return nil, err
+	}
+	return status.Result, nil
+}
+
+func printSubmissionResult(result *godge.SubmissionResponse) {
+	if result.Build != nil && result.Build.Output != "" && len(result.Tests) > 0 {
+		// Failed builds have their output in the submission's error.
+		fmt.Printf("=== Build (%v)\n%v\n\n", result.Build.Duration.Round(time.Millisecond), strings.TrimSpace(result.Build.Output))
+	}
+	if len(result.Tests) > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "TEST\tVERDICT\tDURATION\tMESSAGE")
+		for _, t := range result.Tests {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", t.Name, t.Verdict, t.Duration.Round(time.Millisecond), firstLine(t.Message))
+		}
+		w.Flush()
+
+		for _, t := range result.Tests {
+			if t.Message == firstLine(t.Message) && t.Stdout == "" && t.Stderr == "" {
+				continue
+			}
+			fmt.Printf("\n=== %v\n", t.Name)
+			if t.Message != firstLine(t.Message) {
+				fmt.Println(t.Message)
+			}
+			if t.Stdout != "" {
+				fmt.Printf("--- stdout:\n%v\n", t.Stdout)
+			}
+			if t.Stderr != "" {
+				fmt.Printf("--- stderr:\n%v\n", t.Stderr)
+			}
+		}
+		fmt.Println()
+	}
+
+	if result.MaxScore > 1 {
+		log.Printf("Score: %v/%v", result.Score, result.MaxScore)
+	}
+	if result.Passed {
+		log.Println("You submission passed!")
+	} else if len(result.Tests) > 0 {
+		log.Println("You submission failed!")
+	} else {
+		log.Printf("You submission failed: %v", result.Error)
+	}
+}
+
+// firstLine returns the first line of a possibly multiline message.
+func firstLine(s string) string {
+	if i := strings.Index(s, "\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
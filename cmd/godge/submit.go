@@ -9,23 +9,33 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/MohamedBassem/godge"
 	"github.com/google/subcommands"
 )
 
 type submitCmd struct {
-	language string
-	taskName string
-	username string
-	password string
+	language   string
+	taskName   string
+	username   string
+	password   string
+	wait       bool
+	entrypoint string
+	gitignore  bool
+	dryRun     bool
+
+	// The files of the last packaged submission.
+	files []packagedFile
 }
 
 func (*submitCmd) Name() string     { return "submit" }
 func (*submitCmd) Synopsis() string { return "Submits solution to the server." }
 func (*submitCmd) Usage() string {
-	return `submit -languge <language> -task <taskName> -username <username> -password <password>:
-  Submits solution to the server.
+	return `submit -languge <language> -task <taskName> -username <username> -password <password> [-wait=false] [-entrypoint <script>] [-gitignore] [-dry-run]:
+  Submits solution to the server and waits for its result. The files matching the patterns
+  of .godgeignore (in gitignore syntax) aren't submitted.
 `
 }
 
@@ -34,6 +44,10 @@ func (s *submitCmd) SetFlags(f *flag.FlagSet) {
 	f.StringVar(&s.taskName, "task", "", "The task of the submission")
 	f.StringVar(&s.username, "username", os.Getenv("GODGE_USERNAME"), "Your username")
 	f.StringVar(&s.password, "password", os.Getenv("GODGE_PASSWORD"), "Your password")
+	f.BoolVar(&s.wait, "wait", true, "Wait for the submission to be judged")
+	f.StringVar(&s.entrypoint, "entrypoint", "main.py", "The script to run (Python only)")
+	f.BoolVar(&s.gitignore, "gitignore", false, "Exclude the files ignored by .gitignore too")
+	f.BoolVar(&s.dryRun, "dry-run", false, "List the files that would be submitted without submitting them")
 }
 
 func (s *submitCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
@@ -45,6 +59,18 @@ func (s *submitCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}
 		log.Println("Task must be specified")
 		return subcommands.ExitUsageError
 	}
+	if s.dryRun {
+		pkg, ok := packagers[s.language]
+		if !ok {
+			log.Printf("Unsupported language: %v", s.language)
+			return subcommands.ExitFailure
+		}
+		if err := s.printDryRun(pkg); err != nil {
+			log.Println(err)
+			return subcommands.ExitFailure
+		}
+		return subcommands.ExitSuccess
+	}
 	if s.username == "" {
 		log.Println("Username must be specified")
 		return subcommands.ExitUsageError
@@ -57,19 +83,72 @@ func (s *submitCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}
 		log.Fatal("Server Address must be specified")
 	}
 
-	switch s.language {
-	case "go":
-		if err := s.submit(s.goSubmission); err != nil {
-			log.Println(err)
-			return subcommands.ExitFailure
-		}
-	default:
-		log.Printf("Unsupported language: %v", s.language)
+	if err := checkLanguage(s.language); err != nil {
+		log.Println(err)
+		return subcommands.ExitFailure
+	}
+	pkg, ok := packagers[s.language]
+	if !ok {
+		log.Printf("Language %v is supported by the server, but not by this client. Try updating the client.", s.language)
+		return subcommands.ExitFailure
+	}
+	if err := s.submit(func() (godge.Executor, error) { return pkg(s) }); err != nil {
+		log.Println(err)
 		return subcommands.ExitFailure
 	}
 	return subcommands.ExitSuccess
 }
 
+// packagers package the current dir into the executor of each language.
+var packagers = map[string]func(*submitCmd) (godge.Executor, error){
+	"go":     (*submitCmd).goSubmission,
+	"python": (*submitCmd).pythonSubmission,
+	"docker": (*submitCmd).dockerSubmission,
+}
+
+// checkLanguage makes sure that the server supports the language.
+func checkLanguage(language string) error {
+	resp, err := http.Get(fmt.Sprintf("%v/languages", *serverAddress))
+	if err != nil {
+		return fmt.Errorf("failed to fetch supported languages: %v", err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponseError(resp); err != nil {
+		return fmt.Errorf("fetching supported languages failed: %v", err)
+	}
+	var ls []string
+	if err := json.NewDecoder(resp.Body).Decode(&ls); err != nil {
+		return fmt.Errorf("failed to decode supported languages: %v", err)
+	}
+	for _, l := range ls {
+		if l == language {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported language: %v, the server supports: %v", language, strings.Join(ls, ", "))
+}
+
+// fetchLimits fetches the limits of the submissions accepted by the server. It
+// returns nil if the server doesn't report its limits.
+func fetchLimits() (*godge.Limits, error) {
+	resp, err := http.Get(fmt.Sprintf("%v/limits", *serverAddress))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the server's limits: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err := checkResponseError(resp); err != nil {
+		return nil, fmt.Errorf("fetching the server's limits failed: %v", err)
+	}
+	var limits godge.Limits
+	if err := json.NewDecoder(resp.Body).Decode(&limits); err != nil {
+		return nil, fmt.Errorf("failed to decode the server's limits: %v", err)
+	}
+	return &limits, nil
+}
+
 func (s *submitCmd) submit(executor func() (godge.Executor, error)) error {
 
 	exec, err := executor()
@@ -79,6 +158,7 @@ func (s *submitCmd) submit(executor func() (godge.Executor, error)) error {
 
 	sub := &godge.Submission{
 		Language: s.language,
+		Contest:  *contestName,
 		TaskName: s.taskName,
 		Username: s.username,
 		Executor: exec,
@@ -88,6 +168,14 @@ func (s *submitCmd) submit(executor func() (godge.Executor, error)) error {
 	if err != nil {
 		return fmt.Errorf("failed to marshal request json: %v", err)
 	}
+	limits, err := fetchLimits()
+	if err != nil {
+		return err
+	}
+	if limits != nil && limits.MaxSubmissionSize > 0 && int64(len(reqj)) > limits.MaxSubmissionSize {
+		return fmt.Errorf("the submission (%v) is larger than the server's maximum of %v, make sure that the current dir only contains your project",
+			formatBytes(int64(len(reqj))), formatBytes(limits.MaxSubmissionSize))
+	}
 
 	req, err := http.NewRequest("POST", fmt.Sprintf("%v/submit", *serverAddress), bytes.NewReader(reqj))
 	if err != nil {
@@ -96,7 +184,7 @@ func (s *submitCmd) submit(executor func() (godge.Executor, error)) error {
 	req.SetBasicAuth(s.username, s.password)
 
 	client := &http.Client{
-		Timeout: 0,
+		Timeout: requestTimeout,
 	}
 	resp, err := client.Do(req)
 	if err != nil {
@@ -107,35 +195,86 @@ func (s *submitCmd) submit(executor func() (godge.Executor, error)) error {
 		return fmt.Errorf("submission failed: %v", err)
 	}
 
-	var result godge.SubmissionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var sresp godge.SubmitResponse
+	if err := json.NewDecoder(resp.Body).Decode(&sresp); err != nil {
 		return fmt.Errorf("failed to decode response: %v", err)
 	}
+	log.Printf("Submission %v is queued", sresp.ID)
 
-	if result.Passed {
-		log.Println("You submission passed!")
-	} else {
-		log.Printf("You submission failed: %v", result.Error)
+	if !s.wait {
+		log.Printf("Check its result with: godge status -id %v", sresp.ID)
+		return nil
 	}
 
+	result, err := followSubmission(s.username, s.password, sresp.ID)
+	if err != nil {
+		return err
+	}
+	printSubmissionResult(result)
 	return nil
 }
 
 func (s *submitCmd) goSubmission() (godge.Executor, error) {
+	b, err := s.zipSubmission()
+	if err != nil {
+		return nil, err
+	}
+	return &godge.GoExecutor{
+		PackageArchive: b,
+	}, nil
+}
 
-	// Zip the current dir
+func (s *submitCmd) pythonSubmission() (godge.Executor, error) {
+	if _, err := os.Stat(s.entrypoint); err != nil {
+		return nil, fmt.Errorf("entrypoint %v not found in the current dir", s.entrypoint)
+	}
+	b, err := s.zipSubmission()
+	if err != nil {
+		return nil, err
+	}
+	return &godge.PythonExecutor{
+		PackageArchive: b,
+		Entrypoint:     filepath.ToSlash(s.entrypoint),
+	}, nil
+}
+
+func (s *submitCmd) dockerSubmission() (godge.Executor, error) {
+	b, err := s.zipSubmission()
+	if err != nil {
+		return nil, err
+	}
+	return &godge.DockerExecutor{
+		PackageArchive: b,
+	}, nil
+}
+
+// zipSubmission zips the current dir, which contains the whole project.
+func (s *submitCmd) zipSubmission() ([]byte, error) {
 	currentDir, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get working dir: %v", err)
 	}
 	log.Printf("Will submit %v", currentDir)
-	b, err := zipCurrentDir()
+	b, files, err := zipCurrentDir(s.gitignore)
 	if err != nil {
 		return nil, fmt.Errorf("failed to zip current dir: %v", err)
 	}
-	log.Printf("Done zipping %v", currentDir)
+	s.files = files
+	log.Printf("Done zipping %v (%v)", currentDir, formatBytes(int64(len(b))))
+	return b, nil
+}
 
-	return &godge.GoExecutor{
-		PackageArchive: b,
-	}, nil
+// printDryRun packages the submission and prints the files that would be submitted
+// along with the size of the archive.
+func (s *submitCmd) printDryRun(pkg func(*submitCmd) (godge.Executor, error)) error {
+	if _, err := pkg(s); err != nil {
+		return err
+	}
+	var total int64
+	for _, f := range s.files {
+		fmt.Printf("%v (%v)\n", f.name, formatBytes(f.size))
+		total += f.size
+	}
+	fmt.Printf("\n%v files, %v uncompressed\n", len(s.files), formatBytes(total))
+	return nil
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/MohamedBassem/godge"
 	"github.com/google/subcommands"
@@ -31,7 +32,7 @@ func (s *tasksCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{})
 		log.Fatal("Server Address must be specified")
 	}
 
-	resp, err := http.Get(fmt.Sprintf("%v/tasks", *serverAddress))
+	resp, err := http.Get(fmt.Sprintf("%v/tasks%v", *serverAddress, contestQuery()))
 	if err != nil {
 		log.Printf("Failed to fetch tasks: %v", err)
 		return subcommands.ExitFailure
@@ -51,6 +52,9 @@ func (s *tasksCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{})
 
 	for _, t := range ts {
 		fmt.Printf("%v: %v\n", t.Name, t.Desc)
+		if len(t.Languages) > 0 {
+			fmt.Printf("Languages: %v\n", strings.Join(t.Languages, ", "))
+		}
 		fmt.Println("=============================")
 	}
 
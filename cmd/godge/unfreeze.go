@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/google/subcommands"
+)
+
+type unfreezeCmd struct {
+	token string
+}
+
+func (*unfreezeCmd) Name() string     { return "unfreeze" }
+func (*unfreezeCmd) Synopsis() string { return "Reveals the frozen scoreboard." }
+func (*unfreezeCmd) Usage() string {
+	return `unfreeze -token <adminToken>:
+  Unfreezes the scoreboard of the contest, revealing the results of the submissions judged during the freeze.
+`
+}
+
+func (u *unfreezeCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&u.token, "token", os.Getenv("GODGE_ADMIN_TOKEN"), "The server's admin token")
+}
+
+func (u *unfreezeCmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if u.token == "" {
+		log.Println("Admin token must be specified")
+		return subcommands.ExitUsageError
+	}
+	if *serverAddress == "" {
+		log.Fatal("Server Address must be specified")
+	}
+
+	req, err := http.NewRequest("POST", fmt.Sprintf("%v/admin/unfreeze%v", *serverAddress, contestQuery()), nil)
+	if err != nil {
+		log.Printf("Failed to create request: %v", err)
+		return subcommands.ExitFailure
+	}
+	req.Header.Set("Authorization", "Bearer "+u.token)
+
+	client := &http.Client{
+		Timeout: requestTimeout,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Printf("Failed to unfreeze the scoreboard: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer resp.Body.Close()
+	if err := checkResponseError(resp); err != nil {
+		log.Printf("Unfreezing the scoreboard failed: %v", err)
+		return subcommands.ExitFailure
+	}
+
+	log.Println("The scoreboard is unfrozen")
+	return subcommands.ExitSuccess
+}
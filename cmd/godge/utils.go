@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -14,30 +15,70 @@ import (
 	"github.com/MohamedBassem/godge"
 )
 
-func zipCurrentDir() ([]byte, error) {
+// packagedFile is a file added to the submission's archive.
+type packagedFile struct {
+	name string
+	size int64
+}
+
+// zipCurrentDir zips the current dir, excluding the default ignore patterns and the
+// ones listed in the .godgeignore files (and the .gitignore files if gitignore is set).
+// It returns the archive along with the files added to it.
+func zipCurrentDir(gitignore bool) ([]byte, []packagedFile, error) {
 	dir, err := os.Getwd()
 	if err != nil {
-		return nil, fmt.Errorf("Failed to get current dir: %v", err)
+		return nil, nil, fmt.Errorf("Failed to get current dir: %v", err)
 	}
 	zipfile := new(bytes.Buffer)
 
 	archive := zip.NewWriter(zipfile)
 
 	if _, err := os.Stat(dir); err != nil {
-		return nil, fmt.Errorf("failed to read file stats: %v", err)
+		return nil, nil, fmt.Errorf("failed to read file stats: %v", err)
+	}
+
+	var rules ignoreRules
+	if err := rules.add("", defaultIgnorePatterns); err != nil {
+		return nil, nil, err
 	}
+	var files []packagedFile
 
 	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		name := ""
+		if path != dir {
+			name = strings.Join(strings.Split(strings.TrimPrefix(path, dir+string(os.PathSeparator)), string(os.PathSeparator)), "/")
+			if rules.ignored(name, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if info.IsDir() {
+			// The ignore files apply to their dir and its subdirs.
+			if gitignore {
+				if err := rules.addFile(filepath.Join(path, ".gitignore"), name); err != nil {
+					return err
+				}
+			}
+			if err := rules.addFile(filepath.Join(path, godgeIgnoreFile), name); err != nil {
+				return err
+			}
+		}
+		if path == dir {
+			return nil
+		}
+
 		header, err := zip.FileInfoHeader(info)
 		if err != nil {
 			return err
 		}
-
-		header.Name = strings.Join(strings.Split(strings.TrimPrefix(path, dir), string(os.PathSeparator)), "/")
+		header.Name = name
 
 		if info.IsDir() {
 			header.Name += "/"
@@ -53,11 +94,23 @@ func zipCurrentDir() ([]byte, error) {
 		if info.IsDir() {
 			return nil
 		}
+		files = append(files, packagedFile{name: name, size: info.Size()})
+
+		// Symlinks are archived as links, the server decides whether to accept them.
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("failed to read symlink: %v", err)
+			}
+			_, err = io.WriteString(writer, target)
+			return err
+		}
 
 		file, err := os.Open(path)
 		if err != nil {
 			return fmt.Errorf("failed to read file: %v", err)
 		}
+		defer file.Close()
 
 		if _, err := io.Copy(writer, file); err != nil {
 			return fmt.Errorf("failed to copy file: %v", err)
@@ -65,13 +118,22 @@ func zipCurrentDir() ([]byte, error) {
 		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to walk dir: %v", err)
+		return nil, nil, fmt.Errorf("failed to walk dir: %v", err)
 	}
 
 	if err := archive.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close archive: %v", err)
+		return nil, nil, fmt.Errorf("failed to close archive: %v", err)
 	}
-	return zipfile.Bytes(), nil
+	return zipfile.Bytes(), files, nil
+}
+
+// contestQuery returns the query string selecting the contest, or an empty string
+// for the default contest.
+func contestQuery() string {
+	if *contestName == "" {
+		return ""
+	}
+	return "?" + url.Values{"contest": {*contestName}}.Encode()
 }
 
 func checkResponseError(resp *http.Response) error {
@@ -84,3 +146,17 @@ func checkResponseError(resp *http.Response) error {
 	}
 	return nil
 }
+
+// formatBytes formats the size in a human readable form (e.g. 1.5MB).
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%vB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
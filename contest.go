@@ -0,0 +1,381 @@
+package godge
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The name of the contest holding the tasks registered with Server.RegisterTask.
+// All the users take part in it without joining it.
+const defaultContest = ""
+
+// Contest is a contest or a workshop with its own tasks, participants, scoreboard
+// and time window. Users register once on the server, and join the contests they
+// take part in.
+type Contest struct {
+	// The name that the users use to join the contest and to submit to its tasks.
+	Name string `json:"name"`
+	// A description of the contest.
+	Desc string `json:"desc"`
+	// The tasks of the contest. The task names only need to be unique within the contest.
+	Tasks []Task `json:"-"`
+	// How the scoreboard ranks the participants: ScoringPoints (the default) or
+	// ScoringICPC.
+	Scoring string `json:"scoring"`
+	// The penalty of each rejected submission before the first accepted one in ICPC
+	// scoring (e.g. DefaultPenaltyPerAttempt).
+	PenaltyPerAttempt time.Duration `json:"-"`
+	// The start and the end of the contest. Submissions are only accepted between them.
+	// The start defaults to the time the server starts, and a zero end means that the
+	// contest doesn't end.
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// The public scoreboard stops updating for this duration before the end of the
+	// contest, while the participants still get the verdicts of their own submissions.
+	// The scoreboard is revealed by an admin (see the unfreeze command of the client).
+	FreezeDuration time.Duration `json:"-"`
+}
+
+// contest is a registered contest.
+type contest struct {
+	Contest
+	tasks tasks
+	// Set once an admin unfreezes the scoreboard. It's persisted in the database, and
+	// loaded when the server starts.
+	unfrozen int32
+}
+
+func newContest(c Contest) *contest {
+	ret := &contest{
+		Contest: c,
+		tasks: tasks{
+			m: make(map[string]Task),
+		},
+	}
+	for _, t := range c.Tasks {
+		ret.tasks.set(t.Name, t)
+	}
+	return ret
+}
+
+type contests struct {
+	sync.RWMutex
+	m map[string]*contest
+}
+
+func (c *contests) get(name string) (*contest, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	ret, ok := c.m[name]
+	return ret, ok
+}
+
+func (c *contests) set(name string, ct *contest) {
+	c.Lock()
+	defer c.Unlock()
+	c.m[name] = ct
+}
+
+// all returns the contests sorted by name.
+func (c *contests) all() []*contest {
+	c.RLock()
+	defer c.RUnlock()
+	var ret []*contest
+	for _, v := range c.m {
+		ret = append(ret, v)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// RegisterContest registers a new contest in the server. The contest must have a
+// name, and registering a contest with the name of an already registered one replaces
+// it. The tasks registered with RegisterTask belong to a default contest that all the
+// users take part in.
+func (s *Server) RegisterContest(c Contest) {
+	s.contests.set(c.Name, newContest(c))
+}
+
+// contest returns the contest with the given name, or the default contest if the
+// name is empty.
+func (s *Server) contest(name string) (*contest, bool) {
+	if name == defaultContest {
+		return s.defaultContest, true
+	}
+	return s.contests.get(name)
+}
+
+// allContests returns the default contest followed by the registered ones.
+func (s *Server) allContests() []*contest {
+	return append([]*contest{s.defaultContest}, s.contests.all()...)
+}
+
+// validateContests validates the settings of the contests. The default contest
+// takes its settings from the server's.
+func (s *Server) validateContests() error {
+	s.defaultContest.Scoring = s.Scoring
+	s.defaultContest.PenaltyPerAttempt = s.PenaltyPerAttempt
+	s.defaultContest.Start = s.ContestStart
+	s.defaultContest.End = s.ContestEnd
+	s.defaultContest.FreezeDuration = s.FreezeDuration
+	now := time.Now()
+	for _, c := range s.allContests() {
+		if c != s.defaultContest && c.Name == defaultContest {
+			return fmt.Errorf("the registered contests must have a name")
+		}
+		if err := c.validate(now); err != nil {
+			return fmt.Errorf("invalid settings of %v: %v", c.describe(), err)
+		}
+	}
+	s.ContestStart = s.defaultContest.Start
+	return nil
+}
+
+// allTasks returns the tasks of all the contests.
+func (s *Server) allTasks() []Task {
+	var ret []Task
+	for _, c := range s.allContests() {
+		ret = append(ret, c.tasks.tasks()...)
+	}
+	return ret
+}
+
+// validate checks the settings of the contest, defaulting its start to now.
+func (c *contest) validate(now time.Time) error {
+	switch c.Scoring {
+	case "":
+		c.Scoring = ScoringPoints
+	case ScoringPoints, ScoringICPC:
+	default:
+		return fmt.Errorf("unknown scoring mode %q", c.Scoring)
+	}
+	if c.PenaltyPerAttempt < 0 {
+		return fmt.Errorf("the penalty per attempt must not be negative, got %v", c.PenaltyPerAttempt)
+	}
+	if c.Start.IsZero() {
+		c.Start = now
+	}
+	if !c.End.IsZero() && !c.End.After(c.Start) {
+		return fmt.Errorf("the contest must end after it starts")
+	}
+	if c.FreezeDuration < 0 {
+		return fmt.Errorf("the freeze duration must not be negative, got %v", c.FreezeDuration)
+	}
+	if c.FreezeDuration > 0 && c.End.IsZero() {
+		return fmt.Errorf("freezing the scoreboard requires the contest to have an end")
+	}
+	return nil
+}
+
+// describe returns the name of the contest to be used in messages.
+func (c *contest) describe() string {
+	if c.Name == defaultContest {
+		return "the default contest"
+	}
+	return fmt.Sprintf("contest %v", c.Name)
+}
+
+// checkOpen returns an error if the contest isn't running at the given time.
+func (c *contest) checkOpen(now time.Time) error {
+	if now.Before(c.Start) {
+		return fmt.Errorf("the contest hasn't started yet, it starts at %v", c.Start.Format(time.RFC1123))
+	}
+	if !c.End.IsZero() && !now.Before(c.End) {
+		return fmt.Errorf("the contest is over, it ended at %v", c.End.Format(time.RFC1123))
+	}
+	return nil
+}
+
+// frozenAt returns when the scoreboard got frozen, and whether it's frozen at the
+// given time. It returns a zero time if it's not.
+func (c *contest) frozenAt(now time.Time) (time.Time, bool) {
+	if c.FreezeDuration <= 0 || c.End.IsZero() || atomic.LoadInt32(&c.unfrozen) == 1 {
+		return time.Time{}, false
+	}
+	freeze := c.End.Add(-c.FreezeDuration)
+	if now.Before(freeze) {
+		return time.Time{}, false
+	}
+	return freeze, true
+}
+
+// loadUnfrozen restores the unfreeze of the contest's scoreboard by an admin. An
+// unfreeze only applies to the contest with the same end, so that rerunning the
+// contest freezes its scoreboard again.
+func (c *contest) loadUnfrozen(db *sqlx.DB) error {
+	var ends []time.Time
+	if err := db.Select(&ends, "SELECT contest_end FROM unfrozen_scoreboards WHERE contest=?", c.Name); err != nil {
+		return err
+	}
+	for _, end := range ends {
+		if end.Equal(c.End) {
+			atomic.StoreInt32(&c.unfrozen, 1)
+		}
+	}
+	return nil
+}
+
+// unfreeze reveals the contest's scoreboard.
+func (c *contest) unfreeze(db *sqlx.DB) error {
+	if atomic.LoadInt32(&c.unfrozen) == 1 {
+		return nil
+	}
+	_, err := db.Exec("INSERT INTO unfrozen_scoreboards (contest, contest_end, unfrozen_at) VALUES (?,?,?)", c.Name, c.End, time.Now())
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(&c.unfrozen, 1)
+	return nil
+}
+
+// isParticipant returns whether the user takes part in the contest.
+func (c *contest) isParticipant(db *sqlx.DB, username string) (bool, error) {
+	if c.Name == defaultContest {
+		return true, nil
+	}
+	var n int
+	if err := db.Get(&n, "SELECT COUNT(*) FROM participants WHERE contest=? AND username=?", c.Name, username); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// participants returns the usernames of the contest's participants.
+func (c *contest) participants(db *sqlx.DB) ([]string, error) {
+	if c.Name == defaultContest {
+		return userQ.usernames(db)
+	}
+	var ret []string
+	if err := db.Select(&ret, "SELECT username FROM participants WHERE contest=?", c.Name); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+var errAlreadyJoined = errors.New("the user already joined the contest")
+
+// join adds the user to the contest's participants. It returns errAlreadyJoined if
+// the user is already a participant.
+func (c *contest) join(db *sqlx.DB, username string) error {
+	_, err := db.Exec("INSERT INTO participants (contest, username, joined_at) VALUES (?,?,?)", c.Name, username, time.Now())
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return errAlreadyJoined
+	}
+	return err
+}
+
+// requestContest returns the contest selected by the request's "contest" query
+// parameter, or the default contest if it's missing. It responds with an error if
+// there's no such contest.
+func (s *Server) requestContest(w http.ResponseWriter, req *http.Request) (*contest, bool) {
+	name := req.URL.Query().Get("contest")
+	c, ok := s.contest(name)
+	if !ok {
+		httpJSONError(w, fmt.Sprintf("Contest %v not found", name), http.StatusNotFound)
+		return nil, false
+	}
+	return c, true
+}
+
+// authenticateAdmin checks the admin token sent as a bearer token. The admin
+// endpoints are disabled if the server has no admin token.
+func (s *Server) authenticateAdmin(req *http.Request) bool {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if s.AdminToken == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) == 1
+}
+
+// Handles the contests queries. The default contest isn't listed.
+func (s *Server) contestsHTTPHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		httpJSONError(w, "Only GET requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ret := []Contest{}
+	for _, c := range s.contests.all() {
+		ret = append(ret, c.Contest)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(ret); err != nil {
+		httpJSONError(w, "Failed to encode contests", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Handles the requests to join a contest (/join?contest=<name>).
+func (s *Server) joinHTTPHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		httpJSONError(w, "Only POST requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, ok := s.authenticate(req)
+	if !ok {
+		httpJSONError(w, "Wrong username or password", http.StatusUnauthorized)
+		return
+	}
+	c, ok := s.requestContest(w, req)
+	if !ok {
+		return
+	}
+	if c.Name == defaultContest {
+		httpJSONError(w, "The contest must be specified", http.StatusBadRequest)
+		return
+	}
+
+	err := c.join(s.db, username)
+	if err == errAlreadyJoined {
+		httpJSONError(w, fmt.Sprintf("You already joined contest %v", c.Name), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		httpJSONError(w, fmt.Sprintf("Failed to join contest: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("User %v joined contest %v", username, c.Name)
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// Handles the requests to unfreeze the scoreboard of a contest (/admin/unfreeze),
+// revealing the results of the submissions judged during the freeze.
+func (s *Server) unfreezeHTTPHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		httpJSONError(w, "Only POST requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authenticateAdmin(req) {
+		httpJSONError(w, "Wrong admin token", http.StatusUnauthorized)
+		return
+	}
+	c, ok := s.requestContest(w, req)
+	if !ok {
+		return
+	}
+	// An unfreeze applies to the rest of the contest, so an early one would disable
+	// the freeze altogether.
+	if _, frozen := c.frozenAt(time.Now()); !frozen {
+		httpJSONError(w, "The scoreboard is not frozen", http.StatusBadRequest)
+		return
+	}
+
+	if err := c.unfreeze(s.db); err != nil {
+		httpJSONError(w, fmt.Sprintf("Failed to unfreeze the scoreboard: %v", err), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
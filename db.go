@@ -1,5 +1,35 @@
 package godge
 
+import (
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// migration adds a column introduced after the table was first created. Adding
+// the column fails with a duplicate column error when it already exists, as the
+// migrations run on every start.
+type migration struct {
+	addColumn string
+	// The statements filling the column of the existing rows. They only run when the
+	// column is added.
+	backfill []string
+}
+
+var migrations = []migration{
+	{addColumn: "ALTER TABLE submissions ADD COLUMN build TEXT"},
+	{addColumn: "ALTER TABLE submissions ADD COLUMN score INTEGER NOT NULL DEFAULT 0"},
+	{addColumn: "ALTER TABLE submissions ADD COLUMN max_score INTEGER NOT NULL DEFAULT 0"},
+	{
+		addColumn: "ALTER TABLE scoreboard ADD COLUMN points INTEGER NOT NULL DEFAULT 0",
+		// The passed tasks were worth a single point before the tests had points.
+		backfill: []string{"UPDATE scoreboard SET points = 1 WHERE verdict = 'Passed'"},
+	},
+	// The submissions before the contests were added belong to the default one.
+	{addColumn: "ALTER TABLE submissions ADD COLUMN contest varchar(255) NOT NULL DEFAULT ''"},
+	{addColumn: "ALTER TABLE scoreboard ADD COLUMN contest varchar(255) NOT NULL DEFAULT ''"},
+}
+
 func (s *Server) initDB() error {
 	const schema = `
 	CREATE TABLE IF NOT EXISTS users (
@@ -15,7 +45,64 @@ func (s *Server) initDB() error {
 		verdict varchar(255),
 		submitted_at DATETIME
 	);
+
+	CREATE TABLE IF NOT EXISTS submissions (
+		id varchar(255) PRIMARY KEY,
+		username varchar(255),
+		task_name varchar(255),
+		language varchar(255),
+		status varchar(255),
+		passed BOOLEAN,
+		error TEXT,
+		tests TEXT,
+		submitted_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS unfrozen_scoreboards (
+		contest varchar(255),
+		contest_end DATETIME,
+		unfrozen_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS participants (
+		id INTEGER PRIMARY KEY,
+		contest varchar(255),
+		username varchar(255),
+		joined_at DATETIME
+	);
+
+	-- Drop the duplicates of the participants joined before they were unique.
+	DELETE FROM participants WHERE id NOT IN (SELECT MIN(id) FROM participants GROUP BY contest, username);
+	CREATE UNIQUE INDEX IF NOT EXISTS participants_contest_username ON participants (contest, username);
 	`
-	_, err := s.db.Exec(schema)
-	return err
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		if err := m.apply(s.db); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apply adds the column and backfills it, unless the column already exists.
+func (m migration) apply(db *sqlx.DB) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(m.addColumn); err != nil {
+		if strings.Contains(err.Error(), "duplicate column name") {
+			return nil
+		}
+		return err
+	}
+	for _, stmt := range m.backfill {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
@@ -0,0 +1,247 @@
+package godge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	// The language name of the submissions executed by the DockerExecutor.
+	dockerLanguage = "docker"
+	// The maximum duration of building the submission.
+	dockerBuildTimeout = 10 * time.Minute
+	// The name of the rendered Dockerfile in the submission's build context.
+	dockerfileName = ".godge.Dockerfile"
+)
+
+// DockerConfig describes how the submissions of a task in the "docker" language are
+// built and run. It allows hosting workshops for any stack (e.g. Rust, Java or Node)
+// without writing a new executor.
+//
+// The submission is either built into an image using the Dockerfile, or mounted
+// into a container of the Image where the BuildCmd and then the RunCmd are executed.
+type DockerConfig struct {
+	// The image in which the submission is built and run. Ignored if Dockerfile is set.
+	Image string
+	// A Dockerfile template that builds an image containing the submission. The
+	// submission's files are the build context. The template can refer to {{.WorkDir}}.
+	Dockerfile string
+	// The command that builds the submission in the WorkDir. It's optional and it's
+	// ignored if Dockerfile is set.
+	BuildCmd []string
+	// The command that runs the submission. The arguments of the execution are
+	// appended to it.
+	RunCmd []string
+	// The directory of the submission in the container. Defaults to /app.
+	WorkDir string
+}
+
+func (c *DockerConfig) workDir() string {
+	if c.WorkDir == "" {
+		return "/app"
+	}
+	return c.WorkDir
+}
+
+// validate checks that the config is usable.
+func (c *DockerConfig) validate() error {
+	if c.Image == "" && c.Dockerfile == "" {
+		return fmt.Errorf("either the image or the Dockerfile must be set")
+	}
+	if len(c.RunCmd) == 0 {
+		return fmt.Errorf("the run command must be set")
+	}
+	if _, err := template.New("Dockerfile").Parse(c.Dockerfile); err != nil {
+		return fmt.Errorf("invalid Dockerfile template: %v", err)
+	}
+	return nil
+}
+
+// DockerExecutor implements the Executor interface. It's used in the submit request
+// when the language is "docker", and it builds and runs the submission as described
+// by the task's DockerConfig. You won't deal with the DockerExecutor directly, it's
+// only exposed to be used by the command line client.
+type DockerExecutor struct {
+	baseExecutor
+	// A zip archive containing the project to be executed.
+	PackageArchive []byte `json:"packageArchive"`
+
+	// The dir of the unzipped project on the host.
+	projectDir string
+	// The image built from the task's Dockerfile, if any.
+	image string
+}
+
+// Build builds the submitted project as described by the task's DockerConfig. It's
+// done once per submission.
+func (d *DockerExecutor) Build() (*BuildInfo, error) {
+	return d.build(d.buildProject)
+}
+
+// Execute executes the submitted project with the given arguments.
+func (d *DockerExecutor) Execute(args []string) error {
+	return d.execute(d.prepare, args)
+}
+
+// ExecuteWithInput executes the submitted project with the given arguments and input.
+func (d *DockerExecutor) ExecuteWithInput(args []string, input string) error {
+	return d.executeWithInput(d.prepare, args, input)
+}
+
+// ExecuteInteractive executes the submitted project with the given arguments and
+// returns a session attached to its stdin and stdout.
+func (d *DockerExecutor) ExecuteInteractive(args []string) (*Session, error) {
+	return d.executeInteractive(d.prepare, args)
+}
+
+// buildProject unzips the submitted project, and either builds it into an image or
+// runs the BuildCmd on it.
+func (d *DockerExecutor) buildProject() (string, error) {
+	cfg := d.config.docker
+	if cfg == nil {
+		return "", fmt.Errorf("the task doesn't accept docker submissions")
+	}
+
+	pdir, err := d.unzip(d.PackageArchive)
+	if err != nil {
+		return "", fmt.Errorf("failed to unzip project: %v", err)
+	}
+	d.projectDir = pdir
+
+	if cfg.Dockerfile != "" {
+		return d.buildImage(pdir, cfg)
+	}
+	if len(cfg.BuildCmd) == 0 {
+		return "", nil
+	}
+	build := docker.CreateContainerOptions{
+		Name: randomString(20),
+		Config: &docker.Config{
+			Image:      cfg.Image,
+			Cmd:        cfg.BuildCmd,
+			WorkingDir: cfg.workDir(),
+		},
+		HostConfig: d.hostConfig(d.binds(), d.config.buildNetwork),
+	}
+	output, code, err := d.runToCompletion(build, dockerBuildTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to build project: %v", err)
+	}
+	if code != 0 {
+		output = truncateOutput(output)
+		d.markCompilationError(output)
+		return output, fmt.Errorf("compilation error:\n%v", output)
+	}
+	return output, nil
+}
+
+func (d *DockerExecutor) archive() []byte {
+	return d.PackageArchive
+}
+
+func (d *DockerExecutor) images() []string {
+	cfg := d.config.docker
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Dockerfile != "" {
+		return dockerfileImages(cfg.Dockerfile)
+	}
+	return []string{cfg.Image}
+}
+
+func (d *DockerExecutor) binds() []string {
+	return []string{
+		fmt.Sprintf("%v:%v", d.projectDir, d.config.docker.workDir()),
+	}
+}
+
+// prepare builds the submitted project if it's not built yet and returns the options
+// of the container running it.
+func (d *DockerExecutor) prepare(args []string) (docker.CreateContainerOptions, error) {
+	if _, err := d.Build(); err != nil {
+		return docker.CreateContainerOptions{}, err
+	}
+	cfg := d.config.docker
+	wdir := cfg.workDir()
+	d.workDir = wdir
+
+	// Projects built into an image carry their files, otherwise they're mounted
+	// into the configured image.
+	image, binds := d.image, []string(nil)
+	if image == "" {
+		image, binds = cfg.Image, d.binds()
+	}
+	option := docker.CreateContainerOptions{
+		Name: randomString(20),
+		Config: &docker.Config{
+			Image:      image,
+			Cmd:        append(append([]string{}, cfg.RunCmd...), args...),
+			WorkingDir: wdir,
+		},
+		HostConfig: d.hostConfig(binds, d.config.network),
+	}
+	d.exposePorts(option.Config)
+	return option, nil
+}
+
+// buildImage renders the Dockerfile into the project's dir, and builds the project
+// into an image. It returns the output of the build.
+func (d *DockerExecutor) buildImage(pdir string, cfg *DockerConfig) (string, error) {
+	tmpl, err := template.New("Dockerfile").Parse(cfg.Dockerfile)
+	if err != nil {
+		return "", fmt.Errorf("invalid Dockerfile template: %v", err)
+	}
+	dockerfile := new(bytes.Buffer)
+	if err := tmpl.Execute(dockerfile, struct{ WorkDir string }{cfg.workDir()}); err != nil {
+		return "", fmt.Errorf("failed to render Dockerfile: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(pdir, dockerfileName), dockerfile.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write Dockerfile: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerBuildTimeout)
+	defer cancel()
+	// Image names must be lowercase.
+	image := "godge-" + strings.ToLower(randomString(20))
+	output := new(bytes.Buffer)
+	err = d.dockerClient.BuildImage(docker.BuildImageOptions{
+		Name:                image,
+		Dockerfile:          dockerfileName,
+		ContextDir:          pdir,
+		OutputStream:        output,
+		RmTmpContainer:      true,
+		ForceRmTmpContainer: true,
+		NetworkMode:         d.config.buildNetwork,
+		Memory:              d.config.limits.Memory,
+		Labels:              d.labels(),
+		Context:             ctx,
+	})
+	// A failed build might still leave the image behind.
+	d.resources.addImage(image)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("failed to build image: didn't finish within %v", dockerBuildTimeout)
+		}
+		out := truncateOutput(output.String())
+		// A failed step of the Dockerfile is reported as an error in the build's output,
+		// anything else is a failure of the daemon.
+		if _, ok := err.(*jsonmessage.JSONError); !ok {
+			return out, fmt.Errorf("failed to build image: %v\n%v", err, out)
+		}
+		d.markCompilationError(out)
+		return out, fmt.Errorf("compilation error: %v\n%v", err, out)
+	}
+	d.image = image
+	d.ownImage = true
+	return output.String(), nil
+}
@@ -0,0 +1,140 @@
+package godge
+
+import (
+	"sync"
+)
+
+// The types of the submission events.
+const (
+	// EventQueued is sent whenever the position of the submission in the queue changes.
+	EventQueued = "queued"
+	// EventRunning is sent when a worker picks the submission.
+	EventRunning = "running"
+	// EventBuilding is sent before building the submission.
+	EventBuilding = "building"
+	// EventBuilt is sent after building the submission and it carries the build's info.
+	EventBuilt = "built"
+	// EventTestStarted is sent before running each of the task's tests.
+	EventTestStarted = "testStarted"
+	// EventTestPassed is sent when a test passes.
+	EventTestPassed = "testPassed"
+	// EventTestFailed is sent when a test fails.
+	EventTestFailed = "testFailed"
+	// EventDone is the last event of each submission and it carries its result.
+	EventDone = "done"
+)
+
+// SubmissionEvent reports the progress of a submission. It's streamed to the
+// user while the submission is being judged. It's exposed to be used by the
+// command line client.
+type SubmissionEvent struct {
+	Type string `json:"type"`
+	// The 1-based position of the submission in the queue. Only set for EventQueued.
+	QueuePosition int `json:"queuePosition,omitempty"`
+	// The name of the test. Only set for the test events.
+	Test string `json:"test,omitempty"`
+	// Why the test or the build failed. Only set for EventTestFailed and EventBuilt.
+	Error string `json:"error,omitempty"`
+	// The info of the build. Only set for EventBuilt.
+	Build *BuildInfo `json:"build,omitempty"`
+	// The result of the test. Only set for EventTestPassed and EventTestFailed.
+	TestResult *TestResult `json:"testResult,omitempty"`
+	// The result of the submission. Only set for EventDone.
+	Result *SubmissionResponse `json:"result,omitempty"`
+}
+
+// eventStream holds the events of a single submission. The history is kept so
+// that subscribers joining late get the full picture, except for the outdated
+// positions in the queue.
+type eventStream struct {
+	history     []SubmissionEvent
+	subscribers map[chan SubmissionEvent]struct{}
+}
+
+// submissionEvents dispatches the events of the submissions that are still being
+// judged to their subscribers.
+type submissionEvents struct {
+	sync.Mutex
+	m map[string]*eventStream
+}
+
+// open starts accepting events for the submission with the given id.
+func (e *submissionEvents) open(id string) {
+	e.Lock()
+	defer e.Unlock()
+	e.m[id] = &eventStream{
+		subscribers: make(map[chan SubmissionEvent]struct{}),
+	}
+}
+
+// publish sends the event to all the subscribers of the submission. Publishing
+// EventDone closes the stream of the submission.
+func (e *submissionEvents) publish(id string, ev SubmissionEvent) {
+	e.Lock()
+	defer e.Unlock()
+	st, ok := e.m[id]
+	if !ok {
+		return
+	}
+	if n := len(st.history); ev.Type == EventQueued && n > 0 && st.history[n-1].Type == EventQueued {
+		// Only the latest position in the queue is kept, as it's updated whenever any
+		// submission is queued or dequeued.
+		st.history[n-1] = ev
+	} else {
+		st.history = append(st.history, ev)
+	}
+	for ch := range st.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			// Drop slow subscribers rather than blocking the judge.
+			delete(st.subscribers, ch)
+			close(ch)
+		}
+	}
+	if ev.Type == EventDone {
+		for ch := range st.subscribers {
+			close(ch)
+		}
+		delete(e.m, id)
+	}
+}
+
+// subscribe returns a channel with all the events of the submission so far,
+// followed by the new ones. The channel is closed after EventDone. The returned
+// bool is false if the submission isn't being judged.
+func (e *submissionEvents) subscribe(id string) (chan SubmissionEvent, bool) {
+	e.Lock()
+	defer e.Unlock()
+	st, ok := e.m[id]
+	if !ok {
+		return nil, false
+	}
+	ch := make(chan SubmissionEvent, len(st.history)+100)
+	for _, ev := range st.history {
+		ch <- ev
+	}
+	st.subscribers[ch] = struct{}{}
+	return ch, true
+}
+
+// unsubscribe stops sending the submission's events to the channel.
+func (e *submissionEvents) unsubscribe(id string, ch chan SubmissionEvent) {
+	e.Lock()
+	defer e.Unlock()
+	st, ok := e.m[id]
+	if !ok {
+		return
+	}
+	if _, ok := st.subscribers[ch]; ok {
+		delete(st.subscribers, ch)
+		close(ch)
+	}
+}
+
+// discard drops the stream of the submission without notifying its subscribers.
+func (e *submissionEvents) discard(id string) {
+	e.Lock()
+	defer e.Unlock()
+	delete(e.m, id)
+}
@@ -1,20 +1,27 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/MohamedBassem/godge"
 )
 
-func runAndCompareOutput(sub *godge.Submission, args []string, want string) error {
+func runAndCompareOutput(ctx context.Context, sub *godge.Submission, args []string, want string) error {
 	if err := sub.Executor.Execute(args); err != nil {
 		return err
 	}
 	defer sub.Executor.Stop()
-	<-sub.Executor.DieEvent()
+	select {
+	case <-sub.Executor.DieEvent():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 	got, err := sub.Executor.Stdout()
 	if err != nil {
 		return err
@@ -31,9 +38,10 @@ var tasks = []godge.Task{
 		Desc: "Your program should print 'Hello World!' to stdout.",
 		Tests: []godge.Test{
 			{
-				Name: "PrintsHelloWorld",
-				Func: func(sub *godge.Submission) error {
-					return runAndCompareOutput(sub, []string{}, "Hello World!")
+				Name:    "PrintsHelloWorld",
+				Timeout: 30 * time.Second,
+				Func: func(ctx context.Context, sub *godge.Submission) error {
+					return runAndCompareOutput(ctx, sub, []string{}, "Hello World!")
 				},
 			},
 		},
@@ -44,14 +52,99 @@ var tasks = []godge.Task{
 		Tests: []godge.Test{
 			{
 				Name: "PrintsHelloJudge",
-				Func: func(sub *godge.Submission) error {
-					return runAndCompareOutput(sub, []string{"--name", "Judge"}, "Hello Judge!")
+				Func: func(ctx context.Context, sub *godge.Submission) error {
+					return runAndCompareOutput(ctx, sub, []string{"--name", "Judge"}, "Hello Judge!")
 				},
 			},
 			{
 				Name: "PrintsHelloUser",
-				Func: func(sub *godge.Submission) error {
-					return runAndCompareOutput(sub, []string{"--name", sub.Username}, fmt.Sprintf("Hello %v!", sub.Username))
+				Func: func(ctx context.Context, sub *godge.Submission) error {
+					return runAndCompareOutput(ctx, sub, []string{"--name", sub.Username}, fmt.Sprintf("Hello %v!", sub.Username))
+				},
+			},
+		},
+	},
+	{
+		Name: "Sum",
+		Desc: "The binary should read two integers from stdin and print their sum to stdout.",
+		Tests: []godge.Test{
+			{
+				Name: "SumsTwoNumbers",
+				Func: func(ctx context.Context, sub *godge.Submission) error {
+					if err := sub.Executor.ExecuteWithInput([]string{}, "40 2\n"); err != nil {
+						return err
+					}
+					defer sub.Executor.Stop()
+					select {
+					case <-sub.Executor.DieEvent():
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+					got, err := sub.Executor.Stdout()
+					if err != nil {
+						return err
+					}
+					if strings.TrimSpace(got) != "42" {
+						return fmt.Errorf("want: 42, got: %v", got)
+					}
+					return nil
+				},
+			},
+		},
+	},
+	{
+		Name: "GuessTheNumber",
+		Desc: "The binary should guess a number between 1 and 100. It prints a guess per line, and reads 'higher', 'lower' or 'correct' after each guess.",
+		Tests: []godge.Test{
+			{
+				Name: "GuessesTheNumber",
+				Func: func(ctx context.Context, sub *godge.Submission) error {
+					session, err := sub.Executor.ExecuteInteractive([]string{})
+					if err != nil {
+						return err
+					}
+					defer sub.Executor.Stop()
+					secret := rand.Intn(100) + 1
+					for i := 0; i < 7; i++ {
+						line, err := session.ReadLine(ctx)
+						if err != nil {
+							return fmt.Errorf("failed to read guess: %v", err)
+						}
+						guess, err := strconv.Atoi(strings.TrimSpace(line))
+						if err != nil {
+							return fmt.Errorf("invalid guess %q", line)
+						}
+						switch {
+						case guess < secret:
+							err = session.WriteLine("higher")
+						case guess > secret:
+							err = session.WriteLine("lower")
+						default:
+							return session.WriteLine("correct")
+						}
+						if err != nil {
+							return err
+						}
+					}
+					return fmt.Errorf("didn't guess %v in 7 guesses", secret)
+				},
+			},
+		},
+	},
+	{
+		Name:    "HealthCheck",
+		Desc:    "The binary should start a web server on port 8080 that responds with 200 to GET /healthz.",
+		Network: godge.NetworkRestricted,
+		Ports:   []int{8080},
+		Tests: []godge.Test{
+			{
+				Name: "RespondsOnHealthz",
+				Func: func(ctx context.Context, sub *godge.Submission) error {
+					if err := sub.Executor.Execute([]string{}); err != nil {
+						return err
+					}
+					defer sub.Executor.Stop()
+					return godge.HTTPCheck{Path: "/healthz", WantStatus: 200}.RunOn(ctx, sub.Executor, 8080)
 				},
 			},
 		},
@@ -2,11 +2,47 @@ package godge
 
 import (
 	"fmt"
-	"strings"
+	"time"
 
 	docker "github.com/fsouza/go-dockerclient"
 )
 
+// DefaultGoImage is the default docker image used to build and run the Go submissions.
+const DefaultGoImage = "golang:1.22"
+
+const (
+	// The maximum duration of downloading the dependencies and building the submission.
+	goBuildTimeout = 5 * time.Minute
+	// The path of the built binary relative to the package's dir.
+	goBinary = ".godge/main"
+	// The module path used for the submissions that don't have a go.mod file.
+	goDefaultModule = "submission"
+	// The dir of the package in the containers.
+	goWorkDir = "/src"
+	// The docker volume caching the downloaded modules, shared by all the submissions,
+	// and its path in the containers.
+	goModCacheVolume = "godge-gomodcache"
+	goModCacheDir    = "/godge/gomodcache"
+)
+
+// goDownloadScript downloads the dependencies of the package in the current dir.
+// Submissions without a go.mod are turned into a module, and dependencies are
+// downloaded unless vendored. It only runs the go command, never the submitted code.
+var goDownloadScript = fmt.Sprintf(`
+set -e
+if [ ! -f go.mod ]; then
+	go mod init %v > /dev/null 2>&1
+	go mod tidy
+fi
+if [ ! -d vendor ]; then
+	go mod download
+fi
+`, goDefaultModule)
+
+// goBuildScript builds the package in the current dir into goBinary, after making
+// sure that its dependencies are downloaded.
+var goBuildScript = goDownloadScript + fmt.Sprintf("go build -o %v .\n", goBinary)
+
 // GoExecutor implements the Executor interface. It's used in the submit request
 // when the language is Go. You won't deal with the GoExecutor directly, it's only
 // exposed to be used by the command line client.
@@ -14,49 +50,135 @@ type GoExecutor struct {
 	baseExecutor
 	// A zip archive containing the "main" package to be executed.
 	PackageArchive []byte `json:"packageArchive"`
+
+	// The dir of the unzipped package on the host.
+	packageDir string
+}
+
+// Build builds the submitted package. It's done once per submission, and the built
+// binary is reused by all the executions.
+func (g *GoExecutor) Build() (*BuildInfo, error) {
+	return g.build(g.compile)
 }
 
 // Execute executes the Go main package submitted with the given arguments.
 func (g *GoExecutor) Execute(args []string) error {
-	g.init()
-	if g.dockerClient == nil {
-		// Panic if there's a logic error
-		panic("Docker client must be set for go executor")
+	return g.execute(g.prepare, args)
+}
+
+// ExecuteWithInput executes the Go main package submitted with the given arguments
+// and input.
+func (g *GoExecutor) ExecuteWithInput(args []string, input string) error {
+	return g.executeWithInput(g.prepare, args, input)
+}
+
+// ExecuteInteractive executes the Go main package submitted with the given arguments
+// and returns a session attached to its stdin and stdout.
+func (g *GoExecutor) ExecuteInteractive(args []string) (*Session, error) {
+	return g.executeInteractive(g.prepare, args)
+}
+
+func (g *GoExecutor) image() string {
+	if g.config.goImage == "" {
+		return DefaultGoImage
+	}
+	return g.config.goImage
+}
+
+func (g *GoExecutor) archive() []byte {
+	return g.PackageArchive
+}
+
+func (g *GoExecutor) images() []string {
+	return []string{g.image()}
+}
+
+func (g *GoExecutor) binds() []string {
+	return []string{
+		fmt.Sprintf("%v:%v", g.packageDir, goWorkDir),
 	}
+}
 
-	pdir, err := unzipToTmpDir(g.PackageArchive)
+// compile unzips the submitted package and builds it into goBinary.
+func (g *GoExecutor) compile() (string, error) {
+	pdir, err := g.unzip(g.PackageArchive)
 	if err != nil {
-		return fmt.Errorf("failed to unzip package: %v", err)
+		return "", fmt.Errorf("failed to unzip package: %v", err)
 	}
+	g.packageDir = pdir
 
-	cmd := []string{"/bin/bash", "-c", fmt.Sprintf(`
-	set -e;
-	go-wrapper download > /dev/null 2>&1;
-	go-wrapper install > /dev/null 2>&1;
-	app %v;`, strings.Join(args, " "))}
-	cmd = append(cmd, args...)
-	wdir := "/go/src/app"
-	g.workDir = wdir
-	option := docker.CreateContainerOptions{
+	// The dependencies are downloaded in a separate container, as the build runs the
+	// submitted code (e.g. cgo's directives), so it mustn't have network access.
+	// Once downloaded, the build uses the module cache without a proxy.
+	var downloadOutput string
+	env := []string{"GOMODCACHE=" + goModCacheDir, "GOPROXY=" + g.config.goProxy}
+	cache := fmt.Sprintf("%v:%v", goModCacheVolume, goModCacheDir)
+	if g.config.downloadNetwork != "none" {
+		download := docker.CreateContainerOptions{
+			Name: randomString(20),
+			Config: &docker.Config{
+				Image:      g.image(),
+				Cmd:        []string{"/bin/sh", "-c", goDownloadScript},
+				Env:        env,
+				WorkingDir: goWorkDir,
+			},
+			HostConfig: g.hostConfig(append(g.binds(), cache), g.config.downloadNetwork),
+		}
+		output, err := g.runGoStep(download, "download dependencies")
+		if err != nil {
+			return output, err
+		}
+		downloadOutput = output
+		env = []string{"GOMODCACHE=" + goModCacheDir, "GOPROXY=off"}
+		cache += ":ro"
+	}
+
+	build := docker.CreateContainerOptions{
 		Name: randomString(20),
 		Config: &docker.Config{
-			Image:      "golang:1.8",
-			Cmd:        cmd,
-			WorkingDir: wdir,
-		},
-		HostConfig: &docker.HostConfig{
-			Binds: []string{
-				fmt.Sprintf("%v:%v", pdir, wdir),
-			},
+			Image:      g.image(),
+			Cmd:        []string{"/bin/sh", "-c", goBuildScript},
+			Env:        env,
+			WorkingDir: goWorkDir,
 		},
+		HostConfig: g.hostConfig(append(g.binds(), cache), g.config.buildNetwork),
 	}
+	output, err := g.runGoStep(build, "build package")
+	return downloadOutput + output, err
+}
 
-	g.container, err = g.dockerClient.CreateContainer(option)
+// runGoStep runs the container of a step of the build, and marks the submission as
+// failing to compile if it fails.
+func (g *GoExecutor) runGoStep(option docker.CreateContainerOptions, step string) (string, error) {
+	output, code, err := g.runToCompletion(option, goBuildTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to create container: %v", err)
+		return "", fmt.Errorf("failed to %v: %v", step, err)
 	}
-	if err := g.dockerClient.StartContainer(g.container.ID, nil); err != nil {
-		return fmt.Errorf("failed to start container: %v", err)
+	if code != 0 {
+		output = truncateOutput(output)
+		g.markCompilationError(output)
+		return output, fmt.Errorf("compilation error:\n%v", output)
+	}
+	return output, nil
+}
+
+// prepare builds the submitted package if it's not built yet and returns the options
+// of the container running it.
+func (g *GoExecutor) prepare(args []string) (docker.CreateContainerOptions, error) {
+	if _, err := g.Build(); err != nil {
+		return docker.CreateContainerOptions{}, err
+	}
+	g.workDir = goWorkDir
+
+	option := docker.CreateContainerOptions{
+		Name: randomString(20),
+		Config: &docker.Config{
+			Image:      g.image(),
+			Cmd:        append([]string{goWorkDir + "/" + goBinary}, args...),
+			WorkingDir: goWorkDir,
+		},
+		HostConfig: g.hostConfig(g.binds(), g.config.network),
 	}
-	return nil
+	g.exposePorts(option.Config)
+	return option, nil
 }
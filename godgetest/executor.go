@@ -0,0 +1,303 @@
+// Package godgetest provides a fake executor for testing the definitions of godge
+// tasks without docker. The fake executor runs the "submission" on the host, either
+// as a Go function or as a local binary, so that a regular go test can check that a
+// task accepts a reference solution and rejects the known wrong ones:
+//
+//	sub := &godge.Submission{
+//		Language: "go",
+//		TaskName: task.Name,
+//		Username: "test",
+//		Executor: godgetest.NewFuncExecutor(func(ctx context.Context, p *godgetest.Process) error {
+//			fmt.Fprint(p.Stdout, "Hello World!")
+//			return nil
+//		}),
+//	}
+//	if _, err := task.Run(sub); err != nil {
+//		t.Errorf("reference solution failed: %v", err)
+//	}
+package godgetest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MohamedBassem/godge"
+)
+
+// How long Stop waits for the stopped program to return.
+const stopTimeout = 5 * time.Second
+
+// Process is a single execution of a program.
+type Process struct {
+	// The arguments of the execution.
+	Args []string
+	// The stdin of the execution. It's empty unless the execution has an input or
+	// is interactive.
+	Stdin io.Reader
+	// The stdout and stderr of the execution, returned by the executor's Stdout and
+	// Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+	// The dir that the program runs in (see Executor.Dir).
+	Dir string
+}
+
+// Program is a submission written as a Go function. It's called in its own goroutine
+// for each execution, and it should return once the context is canceled (i.e. when
+// the submission is stopped). A returned error is written to the stderr, similar to
+// a program exiting with a failure.
+type Program func(ctx context.Context, p *Process) error
+
+// Executor is a godge.Executor that runs the program on the host instead of in a
+// container. It has nothing to build, the resource limits and the networking mode
+// of the task don't apply, and the ports that the program listens on are reachable
+// on the loopback interface.
+type Executor struct {
+	// The dir that the program runs in, and that ReadFileFromContainer reads the files
+	// from. Defaults to the current dir.
+	Dir string
+
+	program    Program
+	mu         sync.Mutex
+	started    bool
+	stdout     *syncBuffer
+	stderr     *syncBuffer
+	cancel     context.CancelFunc
+	stdin      io.Closer
+	done       chan struct{}
+	startEvent chan struct{}
+	dieEvent   chan struct{}
+}
+
+// NewFuncExecutor returns an executor running the program.
+func NewFuncExecutor(program Program) *Executor {
+	return &Executor{
+		program:    program,
+		stdout:     new(syncBuffer),
+		stderr:     new(syncBuffer),
+		startEvent: make(chan struct{}, 10),
+		dieEvent:   make(chan struct{}, 10),
+	}
+}
+
+// NewBinaryExecutor returns an executor running the binary at the path (e.g. a
+// reference solution built by the test). Paths without a separator are looked up in
+// the PATH, and relative paths are relative to the current dir.
+func NewBinaryExecutor(path string) *Executor {
+	if strings.ContainsRune(path, filepath.Separator) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	}
+	return NewFuncExecutor(func(ctx context.Context, p *Process) error {
+		cmd := exec.CommandContext(ctx, path, p.Args...)
+		cmd.Dir = p.Dir
+		cmd.Stdout = p.Stdout
+		cmd.Stderr = p.Stderr
+		// The stdin is copied outside of the command, so that waiting for the binary
+		// doesn't wait for the end of an interactive stdin.
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		go func() {
+			io.Copy(stdin, p.Stdin)
+			stdin.Close()
+		}()
+		err = cmd.Wait()
+		if _, ok := err.(*exec.ExitError); ok {
+			// Like the containers, the exit status isn't reported.
+			return nil
+		}
+		return err
+	})
+}
+
+// Build does nothing, the program is already built.
+func (e *Executor) Build() (*godge.BuildInfo, error) {
+	return nil, nil
+}
+
+// Execute runs the program with the provided arguments.
+func (e *Executor) Execute(args []string) error {
+	return e.start(args, nil, nil)
+}
+
+// ExecuteWithInput runs the program with the provided arguments, and the input as
+// its stdin.
+func (e *Executor) ExecuteWithInput(args []string, input string) error {
+	return e.start(args, strings.NewReader(input), nil)
+}
+
+// ExecuteInteractive runs the program with the provided arguments, and returns a
+// session attached to its stdin and stdout.
+func (e *Executor) ExecuteInteractive(args []string) (*godge.Session, error) {
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	if err := e.start(args, inr, outw); err != nil {
+		return nil, err
+	}
+	return godge.NewSession(inw, outr), nil
+}
+
+// start runs the program in the background. If stdin or stdout are not nil, they're
+// used as the stdin and an additional stdout of the program, and they're closed when
+// the program returns.
+func (e *Executor) start(args []string, stdin io.Reader, stdout io.WriteCloser) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	e.mu.Lock()
+	e.started = true
+	e.stdout = new(syncBuffer)
+	e.stderr = new(syncBuffer)
+	e.cancel = cancel
+	e.stdin, _ = stdin.(io.Closer)
+	e.done = done
+	e.startEvent = make(chan struct{}, 10)
+	e.dieEvent = make(chan struct{}, 10)
+	p := &Process{
+		Args:   args,
+		Stdin:  stdin,
+		Stdout: e.stdout,
+		Stderr: e.stderr,
+		Dir:    e.Dir,
+	}
+	dieEvent := e.dieEvent
+	e.startEvent <- struct{}{}
+	e.mu.Unlock()
+
+	if p.Stdin == nil {
+		p.Stdin = strings.NewReader("")
+	}
+	if stdout != nil {
+		p.Stdout = io.MultiWriter(p.Stdout, stdout)
+	}
+
+	go func() {
+		defer func() {
+			if stdout != nil {
+				stdout.Close()
+			}
+			if c, ok := stdin.(io.Closer); ok {
+				c.Close()
+			}
+			close(done)
+			dieEvent <- struct{}{}
+		}()
+		if err := run(ctx, e.program, p); err != nil {
+			fmt.Fprintln(p.Stderr, err)
+		}
+	}()
+	return nil
+}
+
+// run calls the program, converting its panics to errors.
+func run(ctx context.Context, program Program, p *Process) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return program(ctx, p)
+}
+
+// ReadFileFromContainer reads the file at the path relative to the executor's dir.
+func (e *Executor) ReadFileFromContainer(path string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(e.Dir, filepath.FromSlash(path)))
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %v", err)
+	}
+	return string(b), nil
+}
+
+// Stdout returns what the last execution wrote to its stdout.
+func (e *Executor) Stdout() (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stdout.String(), nil
+}
+
+// Stderr returns what the last execution wrote to its stderr.
+func (e *Executor) Stderr() (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.stderr.String(), nil
+}
+
+// Address returns the loopback address of the port, as the program runs on the host.
+func (e *Executor) Address(port int) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.started {
+		return "", fmt.Errorf("the submission is not running")
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), nil
+}
+
+// Stop cancels the context of the running program, closes its stdin and waits for
+// it to return.
+func (e *Executor) Stop() error {
+	e.mu.Lock()
+	cancel, stdin, done := e.cancel, e.stdin, e.done
+	e.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	if stdin != nil {
+		// Unblocks the program if it's reading its stdin.
+		stdin.Close()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(stopTimeout):
+		return fmt.Errorf("the program didn't return %v after being stopped", stopTimeout)
+	}
+}
+
+// StartEvent returns a channel that gets signaled when the program starts.
+func (e *Executor) StartEvent() chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.startEvent
+}
+
+// DieEvent returns a channel that gets signaled when the program returns.
+func (e *Executor) DieEvent() chan struct{} {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.dieEvent
+}
+
+// syncBuffer is a bytes.Buffer that's safe to write to while it's read.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
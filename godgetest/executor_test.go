@@ -0,0 +1,196 @@
+package godgetest_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MohamedBassem/godge"
+	"github.com/MohamedBassem/godge/godgetest"
+)
+
+// greetTask expects the submission to greet the name it's given as an argument.
+var greetTask = godge.Task{
+	Name: "Greet",
+	Tests: []godge.Test{
+		{
+			Name: "GreetsTheName",
+			Func: func(ctx context.Context, sub *godge.Submission) error {
+				if err := sub.Executor.Execute([]string{"gopher"}); err != nil {
+					return err
+				}
+				select {
+				case <-sub.Executor.DieEvent():
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				stdout, err := sub.Executor.Stdout()
+				if err != nil {
+					return err
+				}
+				if want := "Hello gopher!"; stdout != want {
+					return fmt.Errorf("expected %q, got %q", want, stdout)
+				}
+				return nil
+			},
+		},
+	},
+}
+
+func greet(greeting string) godgetest.Program {
+	return func(ctx context.Context, p *godgetest.Process) error {
+		fmt.Fprintf(p.Stdout, "%v %v!", greeting, p.Args[0])
+		return nil
+	}
+}
+
+func submission(e godge.Executor) *godge.Submission {
+	return &godge.Submission{
+		Language: "go",
+		TaskName: greetTask.Name,
+		Username: "test",
+		Executor: e,
+	}
+}
+
+func TestRunPassingProgram(t *testing.T) {
+	results, err := greetTask.Run(submission(godgetest.NewFuncExecutor(greet("Hello"))))
+	if err != nil {
+		t.Fatalf("Run() = %v, want nil", err)
+	}
+	if len(results) != 1 || results[0].Verdict != "Passed" {
+		t.Errorf("Run() results = %+v, want a single passed test", results)
+	}
+}
+
+func TestRunFailingProgram(t *testing.T) {
+	results, err := greetTask.Run(submission(godgetest.NewFuncExecutor(greet("Bye"))))
+	if err == nil {
+		t.Fatal("Run() = nil, want an error")
+	}
+	if len(results) != 1 || results[0].Verdict != "Failed" || !strings.Contains(results[0].Message, `got "Bye gopher!"`) {
+		t.Errorf("Run() results = %+v, want a single failed test", results)
+	}
+}
+
+func TestProgramErrorsGoToStderr(t *testing.T) {
+	e := godgetest.NewFuncExecutor(func(ctx context.Context, p *godgetest.Process) error {
+		panic("boom")
+	})
+	if err := e.Execute(nil); err != nil {
+		t.Fatalf("Execute() = %v, want nil", err)
+	}
+	<-e.DieEvent()
+	if stderr, _ := e.Stderr(); !strings.Contains(stderr, "panic: boom") {
+		t.Errorf("Stderr() = %q, want the panic", stderr)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	stopped := make(chan struct{})
+	e := godgetest.NewFuncExecutor(func(ctx context.Context, p *godgetest.Process) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	})
+	if err := e.Execute(nil); err != nil {
+		t.Fatalf("Execute() = %v, want nil", err)
+	}
+	select {
+	case <-e.StartEvent():
+	case <-time.After(time.Second):
+		t.Fatal("the start event wasn't sent")
+	}
+	select {
+	case <-e.DieEvent():
+		t.Fatal("the die event was sent before the program returned")
+	default:
+	}
+
+	if err := e.Stop(); err != nil {
+		t.Fatalf("Stop() = %v, want nil", err)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("the program's context wasn't canceled")
+	}
+	select {
+	case <-e.DieEvent():
+	case <-time.After(time.Second):
+		t.Fatal("the die event wasn't sent")
+	}
+}
+
+func TestReadFileFromContainer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "godgetest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e := godgetest.NewFuncExecutor(func(ctx context.Context, p *godgetest.Process) error {
+		if err := os.MkdirAll(filepath.Join(p.Dir, "out"), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(p.Dir, "out", "result.txt"), []byte("42"), 0644)
+	})
+	e.Dir = dir
+	if err := e.Execute(nil); err != nil {
+		t.Fatalf("Execute() = %v, want nil", err)
+	}
+	<-e.DieEvent()
+
+	got, err := e.ReadFileFromContainer("out/result.txt")
+	if err != nil || got != "42" {
+		t.Errorf("ReadFileFromContainer() = %q, %v, want %q", got, err, "42")
+	}
+	if _, err := e.ReadFileFromContainer("missing.txt"); err == nil {
+		t.Error("ReadFileFromContainer() of a missing file = nil error, want an error")
+	}
+}
+
+func TestExecuteInteractive(t *testing.T) {
+	e := godgetest.NewFuncExecutor(func(ctx context.Context, p *godgetest.Process) error {
+		s := bufio.NewScanner(p.Stdin)
+		for s.Scan() {
+			fmt.Fprintf(p.Stdout, "echo: %v\n", s.Text())
+		}
+		return s.Err()
+	})
+	sess, err := e.ExecuteInteractive(nil)
+	if err != nil {
+		t.Fatalf("ExecuteInteractive() = %v, want nil", err)
+	}
+	defer e.Stop()
+	if err := sess.WriteLine("ping"); err != nil {
+		t.Fatalf("WriteLine() = %v, want nil", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	line, err := sess.ReadLine(ctx)
+	if err != nil || line != "echo: ping" {
+		t.Errorf("ReadLine() = %q, %v, want %q", line, err, "echo: ping")
+	}
+}
+
+func ExampleNewFuncExecutor() {
+	sub := &godge.Submission{
+		Language: "go",
+		TaskName: greetTask.Name,
+		Username: "test",
+		Executor: godgetest.NewFuncExecutor(func(ctx context.Context, p *godgetest.Process) error {
+			fmt.Fprintf(p.Stdout, "Hello %v!", p.Args[0])
+			return nil
+		}),
+	}
+	results, err := greetTask.Run(sub)
+	fmt.Println(results[0].Name, results[0].Verdict, err)
+	// Output: GreetsTheName Passed <nil>
+}
@@ -0,0 +1,110 @@
+package godge
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WaitForPort blocks until something is listening on the given address (host:port)
+// or the context is done.
+func WaitForPort(ctx context.Context, addr string) error {
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("nothing is listening on %v: %v", addr, err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// HTTPCheck describes an HTTP request to send to the submission along with the
+// expectations of its response. Empty expectations are not checked.
+type HTTPCheck struct {
+	// The method of the request. Defaults to GET.
+	Method string
+	// The path of the request (e.g. "/healthz").
+	Path string
+	// The headers of the request.
+	Header http.Header
+	// The body of the request.
+	Body string
+
+	// The expected status code of the response.
+	WantStatus int
+	// Headers that the response must have with the given values.
+	WantHeaders map[string]string
+	// The exact expected body of the response (surrounding whitespace is ignored).
+	WantBody string
+	// A string that the body of the response must contain.
+	WantBodyContains string
+}
+
+// Run sends the request to the given address (host:port) and checks the response.
+// It returns a descriptive error if the response doesn't match the expectations.
+func (c HTTPCheck) Run(ctx context.Context, addr string) error {
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%v%v", addr, c.Path), strings.NewReader(c.Body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	for k, vs := range c.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%v %v failed: %v", method, c.Path, err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read the response of %v %v: %v", method, c.Path, err)
+	}
+
+	if c.WantStatus != 0 && resp.StatusCode != c.WantStatus {
+		return fmt.Errorf("%v %v: want status %v, got: %v", method, c.Path, c.WantStatus, resp.StatusCode)
+	}
+	for k, want := range c.WantHeaders {
+		if got := resp.Header.Get(k); got != want {
+			return fmt.Errorf("%v %v: want header %v: %q, got: %q", method, c.Path, k, want, got)
+		}
+	}
+	body := strings.TrimSpace(string(b))
+	if c.WantBody != "" && body != strings.TrimSpace(c.WantBody) {
+		return fmt.Errorf("%v %v: want body: %q, got: %q", method, c.Path, c.WantBody, body)
+	}
+	if c.WantBodyContains != "" && !strings.Contains(body, c.WantBodyContains) {
+		return fmt.Errorf("%v %v: want body containing: %q, got: %q", method, c.Path, c.WantBodyContains, body)
+	}
+	return nil
+}
+
+// RunOn waits for the submission to listen on the given container port and then
+// runs the check against it. The submission must be already executing.
+func (c HTTPCheck) RunOn(ctx context.Context, e Executor, port int) error {
+	addr, err := e.Address(port)
+	if err != nil {
+		return err
+	}
+	if err := WaitForPort(ctx, addr); err != nil {
+		return err
+	}
+	return c.Run(ctx, addr)
+}
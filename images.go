@@ -0,0 +1,88 @@
+package godge
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// requiredImages returns the images used by the executors of the registered tasks,
+// in all the languages accepted by each task.
+func (s *Server) requiredImages() []string {
+	seen := make(map[string]bool)
+	var ret []string
+	for _, t := range s.allTasks() {
+		for _, l := range t.acceptedLanguages() {
+			e, err := newExecutor(l)
+			if err != nil {
+				continue
+			}
+			de, ok := e.(dockerExecutor)
+			if !ok {
+				continue
+			}
+			de.setConfig(s.executorConfig(t, l))
+			for _, image := range de.images() {
+				if !seen[image] {
+					seen[image] = true
+					ret = append(ret, image)
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// pullImages makes sure that the images are available to the docker daemon, pulling
+// the missing ones.
+func pullImages(dc *docker.Client, images []string) error {
+	for _, image := range images {
+		_, err := dc.InspectImage(image)
+		if err == nil {
+			continue
+		}
+		if err != docker.ErrNoSuchImage {
+			return fmt.Errorf("failed to inspect image %v: %v", image, err)
+		}
+		log.Printf("Pulling image %v ..", image)
+		repo, tag := docker.ParseRepositoryTag(image)
+		if tag == "" {
+			tag = "latest"
+		}
+		if err := dc.PullImage(docker.PullImageOptions{Repository: repo, Tag: tag}, docker.AuthConfiguration{}); err != nil {
+			return fmt.Errorf("failed to pull image %v: %v", image, err)
+		}
+	}
+	return nil
+}
+
+// dockerfileImages returns the images that the Dockerfile builds on, excluding the
+// previous stages of multi-stage builds and the images that depend on build args.
+func dockerfileImages(dockerfile string) []string {
+	stages := map[string]bool{"scratch": true}
+	var ret []string
+	for _, line := range strings.Split(dockerfile, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+		fields = fields[1:]
+		// Flags such as --platform.
+		for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		image := fields[0]
+		if !stages[strings.ToLower(image)] && !strings.ContainsAny(image, "${") {
+			ret = append(ret, image)
+		}
+		if len(fields) >= 3 && strings.EqualFold(fields[1], "AS") {
+			stages[strings.ToLower(fields[2])] = true
+		}
+	}
+	return ret
+}
@@ -0,0 +1,85 @@
+package godge
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Session is a connection to the stdin and stdout of a running submission. It's
+// used by the tests of interactive tasks (e.g. a guessing game) to talk to the
+// submission line by line.
+type Session struct {
+	stdin      io.WriteCloser
+	lines      chan string
+	readErr    error
+	closeStdin sync.Once
+}
+
+// NewSession creates a session that writes to the stdin of the submission and reads
+// its stdout line by line. It's exposed to be used by custom executors.
+func NewSession(stdin io.WriteCloser, stdout io.Reader) *Session {
+	s := &Session{
+		stdin: stdin,
+		lines: make(chan string, 1024),
+	}
+	go func() {
+		defer close(s.lines)
+		r := bufio.NewReader(stdout)
+		for {
+			line, err := r.ReadString('\n')
+			if line != "" {
+				s.lines <- strings.TrimRight(line, "\r\n")
+			}
+			if err != nil {
+				if err != io.EOF {
+					s.readErr = err
+				}
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// Write writes to the stdin of the submission.
+func (s *Session) Write(p []byte) (int, error) {
+	return s.stdin.Write(p)
+}
+
+// WriteLine writes the line followed by a new line to the stdin of the submission.
+func (s *Session) WriteLine(line string) error {
+	if _, err := io.WriteString(s.stdin, line+"\n"); err != nil {
+		return fmt.Errorf("failed to write to stdin: %v", err)
+	}
+	return nil
+}
+
+// ReadLine returns the next line printed by the submission to stdout, without the
+// trailing new line. It returns io.EOF when the submission closes its stdout.
+func (s *Session) ReadLine(ctx context.Context) (string, error) {
+	select {
+	case line, ok := <-s.lines:
+		if !ok {
+			if s.readErr != nil {
+				return "", fmt.Errorf("failed to read from stdout: %v", s.readErr)
+			}
+			return "", io.EOF
+		}
+		return line, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// CloseStdin closes the stdin of the submission, signaling the end of its input.
+func (s *Session) CloseStdin() error {
+	var err error
+	s.closeStdin.Do(func() {
+		err = s.stdin.Close()
+	})
+	return err
+}
@@ -0,0 +1,58 @@
+package godge
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ExecutorFactory creates a new, empty executor for a language. The submission's
+// language specific JSON is unmarshalled into the returned executor, so it must
+// be a pointer.
+type ExecutorFactory func() Executor
+
+var languages = struct {
+	sync.RWMutex
+	m map[string]ExecutorFactory
+}{
+	m: make(map[string]ExecutorFactory),
+}
+
+func init() {
+	RegisterLanguage("go", func() Executor { return &GoExecutor{} })
+	RegisterLanguage("python", func() Executor { return &PythonExecutor{} })
+	RegisterLanguage(dockerLanguage, func() Executor { return &DockerExecutor{} })
+}
+
+// RegisterLanguage makes the language available for submissions. The factory is
+// used to create the executor of each submission in this language. Registering
+// an already registered language replaces its factory. RegisterLanguage must be
+// called before starting the server.
+func RegisterLanguage(name string, factory ExecutorFactory) {
+	languages.Lock()
+	defer languages.Unlock()
+	languages.m[name] = factory
+}
+
+// newExecutor creates a new executor for the given language.
+func newExecutor(language string) (Executor, error) {
+	languages.RLock()
+	defer languages.RUnlock()
+	f, ok := languages.m[language]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language %v", language)
+	}
+	return f(), nil
+}
+
+// supportedLanguages returns the sorted names of the registered languages.
+func supportedLanguages() []string {
+	languages.RLock()
+	defer languages.RUnlock()
+	var ret []string
+	for k := range languages.m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
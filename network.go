@@ -0,0 +1,72 @@
+package godge
+
+import (
+	"fmt"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// The networking modes of the submissions' containers.
+const (
+	// NetworkNone disables networking in the container. It's the default.
+	NetworkNone = "none"
+	// NetworkRestricted attaches the container to an internal network managed by
+	// the judge. The container can't reach the internet or the venue's LAN, but
+	// the judge (and other containers on the same network) can reach it.
+	NetworkRestricted = "restricted"
+	// NetworkOpen attaches the container to docker's default bridge network,
+	// giving it full egress access.
+	NetworkOpen = "open"
+)
+
+// The default sources of the submissions' dependencies.
+const (
+	// DefaultGoProxy is the default Go module proxy. It doesn't fall back to fetching
+	// the modules directly from their repositories.
+	DefaultGoProxy = "https://proxy.golang.org"
+	// DefaultPipIndexURL is the default Python package index.
+	DefaultPipIndexURL = "https://pypi.org/simple"
+)
+
+// The name of the internal docker network used for NetworkRestricted.
+const restrictedNetworkName = "godge-restricted"
+
+// dockerNetworkMode returns the docker network mode of the given networking mode.
+func dockerNetworkMode(network string) (string, error) {
+	switch network {
+	case "", NetworkNone:
+		return "none", nil
+	case NetworkRestricted:
+		return restrictedNetworkName, nil
+	case NetworkOpen:
+		return "bridge", nil
+	default:
+		return "", fmt.Errorf("unknown network mode %q", network)
+	}
+}
+
+// ensureRestrictedNetwork creates the internal network used by the restricted
+// containers if it doesn't exist.
+func ensureRestrictedNetwork(dc *docker.Client) error {
+	nets, err := dc.FilteredListNetworks(docker.NetworkFilterOpts{
+		"name": {restrictedNetworkName: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list networks: %v", err)
+	}
+	for _, n := range nets {
+		if n.Name == restrictedNetworkName {
+			return nil
+		}
+	}
+	_, err = dc.CreateNetwork(docker.CreateNetworkOptions{
+		Name:           restrictedNetworkName,
+		Driver:         "bridge",
+		Internal:       true,
+		CheckDuplicate: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create network %v: %v", restrictedNetworkName, err)
+	}
+	return nil
+}
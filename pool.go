@@ -0,0 +1,282 @@
+package godge
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// The script that runs the submission's command in the pooled containers. It's
+// uploaded to the container right before starting it.
+const poolLauncher = "/.godge-launch"
+
+// containerPool keeps idle containers created ahead of time, so that running a
+// submission only pays for starting its container. The pooled containers are
+// created with the same options as the ones they replace, except for their command
+// (which is a launcher uploaded before starting them) and their binds (whose content
+// is uploaded instead). Each container is used once.
+//
+// The pool of a certain set of options is filled after the first container with
+// these options is requested, as the options depend on the task and the submission.
+type containerPool struct {
+	sync.Mutex
+	dockerClient *docker.Client
+	// The number of idle containers to keep per language.
+	sizes map[string]int
+	// The idle containers and the options they're created with, by pool key.
+	idle      map[string][]string
+	templates map[string]docker.CreateContainerOptions
+	filling   map[string]bool
+}
+
+func newContainerPool(dc *docker.Client, sizes map[string]int) *containerPool {
+	return &containerPool{
+		dockerClient: dc,
+		sizes:        sizes,
+		idle:         make(map[string][]string),
+		templates:    make(map[string]docker.CreateContainerOptions),
+		filling:      make(map[string]bool),
+	}
+}
+
+// poolKey identifies the containers that are interchangeable with the one created
+// with the given options.
+func poolKey(language string, option docker.CreateContainerOptions) (string, error) {
+	cfg := *option.Config
+	cfg.Cmd = nil
+	cfg.Labels = nil
+	var hc docker.HostConfig
+	if option.HostConfig != nil {
+		hc = *option.HostConfig
+	}
+	hc.Binds = nil
+	b, err := json.Marshal(struct {
+		Language   string
+		Config     docker.Config
+		HostConfig docker.HostConfig
+	}{language, cfg, hc})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// take returns an idle container equivalent to the one created with the given options,
+// with the binds' content and the command uploaded to it. It returns false if there
+// are no idle containers, in which case the caller should create the container itself.
+func (p *containerPool) take(language string, option docker.CreateContainerOptions) (*docker.Container, bool) {
+	if p.sizes[language] <= 0 {
+		return nil, false
+	}
+	key, err := poolKey(language, option)
+	if err != nil {
+		return nil, false
+	}
+
+	p.Lock()
+	var id string
+	if ids := p.idle[key]; len(ids) > 0 {
+		id = ids[len(ids)-1]
+		p.idle[key] = ids[:len(ids)-1]
+	}
+	if _, ok := p.templates[key]; !ok {
+		p.templates[key] = poolTemplate(option)
+	}
+	p.Unlock()
+	go p.fill(language, key)
+
+	if id == "" {
+		return nil, false
+	}
+	if err := p.upload(id, option); err != nil {
+		log.Printf("failed to prepare pooled container %v: %v", id, err)
+		p.remove(id)
+		return nil, false
+	}
+	return &docker.Container{ID: id}, true
+}
+
+// poolTemplate returns the options of the pooled containers that replace the container
+// created with the given options.
+func poolTemplate(option docker.CreateContainerOptions) docker.CreateContainerOptions {
+	cfg := *option.Config
+	cfg.Entrypoint = []string{"/bin/sh"}
+	cfg.Cmd = []string{poolLauncher}
+	cfg.Labels = map[string]string{labelGodge: "true"}
+	var hc docker.HostConfig
+	if option.HostConfig != nil {
+		hc = *option.HostConfig
+	}
+	hc.Binds = nil
+	return docker.CreateContainerOptions{
+		Config:     &cfg,
+		HostConfig: &hc,
+	}
+}
+
+// fill creates idle containers until the pool of the key is full.
+func (p *containerPool) fill(language, key string) {
+	p.Lock()
+	if p.filling[key] {
+		p.Unlock()
+		return
+	}
+	p.filling[key] = true
+	p.Unlock()
+	defer func() {
+		p.Lock()
+		p.filling[key] = false
+		p.Unlock()
+	}()
+
+	for {
+		p.Lock()
+		full := len(p.idle[key]) >= p.sizes[language]
+		option, ok := p.templates[key]
+		p.Unlock()
+		if full || !ok {
+			// The pool is full or its image was removed.
+			return
+		}
+		option.Name = randomString(20)
+		c, err := p.dockerClient.CreateContainer(option)
+		if err != nil {
+			log.Printf("failed to create pooled %v container: %v", language, err)
+			return
+		}
+		p.Lock()
+		_, ok = p.templates[key]
+		if ok {
+			p.idle[key] = append(p.idle[key], c.ID)
+		}
+		p.Unlock()
+		if !ok {
+			p.remove(c.ID)
+			return
+		}
+	}
+}
+
+// upload copies the content of the binds and the launcher running the command into
+// the container.
+func (p *containerPool) upload(id string, option docker.CreateContainerOptions) error {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	if option.HostConfig != nil {
+		for _, bind := range option.HostConfig.Binds {
+			parts := strings.Split(bind, ":")
+			if len(parts) < 2 {
+				return fmt.Errorf("invalid bind %v", bind)
+			}
+			if err := tarDir(tw, parts[0], strings.TrimPrefix(parts[1], "/")); err != nil {
+				return err
+			}
+		}
+	}
+
+	var quoted []string
+	for _, arg := range option.Config.Cmd {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	launcher := "exec " + strings.Join(quoted, " ") + "\n"
+	hdr := &tar.Header{
+		Name: strings.TrimPrefix(poolLauncher, "/"),
+		Mode: 0755,
+		Size: int64(len(launcher)),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(tw, launcher); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+
+	return p.dockerClient.UploadToContainer(id, docker.UploadToContainerOptions{
+		InputStream: buf,
+		Path:        "/",
+	})
+}
+
+// evict removes the idle containers of the image and forgets their options, as
+// they can't be used once the image is removed.
+func (p *containerPool) evict(image string) {
+	p.Lock()
+	var ids []string
+	for key, option := range p.templates {
+		if option.Config.Image != image {
+			continue
+		}
+		ids = append(ids, p.idle[key]...)
+		delete(p.idle, key)
+		delete(p.templates, key)
+	}
+	p.Unlock()
+	for _, id := range ids {
+		p.remove(id)
+	}
+}
+
+func (p *containerPool) remove(id string) {
+	p.dockerClient.RemoveContainer(docker.RemoveContainerOptions{
+		ID:            id,
+		RemoveVolumes: true,
+		Force:         true,
+	})
+}
+
+// tarDir writes the content of the dir to the archive under the given prefix.
+func tarDir(tw *tar.Writer, dir, prefix string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = prefix
+		if rel != "." {
+			hdr.Name = prefix + "/" + filepath.ToSlash(rel)
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+// shellQuote quotes the string to be used as a single word in sh.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
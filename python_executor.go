@@ -0,0 +1,188 @@
+package godge
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+const (
+	pythonImage = "python:3"
+	// The maximum duration of installing the requirements of the submission.
+	pythonBuildTimeout = 5 * time.Minute
+	// The entrypoint used when the submission doesn't specify one.
+	defaultPythonEntrypoint = "main.py"
+	// The dir of the project in the containers.
+	pythonWorkDir = "/usr/src/app"
+	// The dir of the installed requirements relative to the project's dir.
+	pythonDepsDir = ".godge/deps"
+	// The dir of the downloaded requirements relative to the project's dir.
+	pythonWheelsDir = ".godge/wheels"
+)
+
+// PythonExecutor implements the Executor interface. It's used in the submit request
+// when the language is Python. You won't deal with the PythonExecutor directly, it's
+// only exposed to be used by the command line client.
+type PythonExecutor struct {
+	baseExecutor
+	// A zip archive containing the project to be executed. If the project has a
+	// requirements.txt in its root, the requirements are installed before running it.
+	PackageArchive []byte `json:"packageArchive"`
+	// The path of the script to run, relative to the project's root. Defaults to main.py.
+	Entrypoint string `json:"entrypoint"`
+
+	// The dir of the unzipped project on the host.
+	projectDir string
+}
+
+// Build installs the requirements of the submitted project. It's done once per submission.
+func (p *PythonExecutor) Build() (*BuildInfo, error) {
+	return p.build(p.installRequirements)
+}
+
+// Execute executes the Python project submitted with the given arguments.
+func (p *PythonExecutor) Execute(args []string) error {
+	return p.execute(p.prepare, args)
+}
+
+// ExecuteWithInput executes the Python project submitted with the given arguments
+// and input.
+func (p *PythonExecutor) ExecuteWithInput(args []string, input string) error {
+	return p.executeWithInput(p.prepare, args, input)
+}
+
+// ExecuteInteractive executes the Python project submitted with the given arguments
+// and returns a session attached to its stdin and stdout.
+func (p *PythonExecutor) ExecuteInteractive(args []string) (*Session, error) {
+	return p.executeInteractive(p.prepare, args)
+}
+
+// entrypoint returns the cleaned path of the script to run.
+func (p *PythonExecutor) entrypoint() (string, error) {
+	entrypoint := p.Entrypoint
+	if entrypoint == "" {
+		entrypoint = defaultPythonEntrypoint
+	}
+	entrypoint = path.Clean(entrypoint)
+	if path.IsAbs(entrypoint) || entrypoint == ".." || strings.HasPrefix(entrypoint, "../") {
+		return "", fmt.Errorf("entrypoint %v is outside the project", p.Entrypoint)
+	}
+	return entrypoint, nil
+}
+
+// installRequirements unzips the submitted project and installs its requirements.
+func (p *PythonExecutor) installRequirements() (string, error) {
+	entrypoint, err := p.entrypoint()
+	if err != nil {
+		return "", err
+	}
+	pdir, err := p.unzip(p.PackageArchive)
+	if err != nil {
+		return "", fmt.Errorf("failed to unzip project: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(pdir, filepath.FromSlash(entrypoint))); err != nil {
+		return "", fmt.Errorf("entrypoint %v not found in the project", entrypoint)
+	}
+	p.projectDir = pdir
+
+	if _, err := os.Stat(filepath.Join(pdir, "requirements.txt")); err != nil {
+		return "", nil
+	}
+	// The requirements are downloaded in a separate container, and installed without
+	// network access, as installing a source distribution runs its setup.py. Only
+	// wheels are downloaded, as downloading a source distribution runs it too.
+	var downloadOutput string
+	source := []string{"--index-url", p.config.pipIndexURL}
+	if p.config.downloadNetwork != "none" {
+		download := docker.CreateContainerOptions{
+			Name: randomString(20),
+			Config: &docker.Config{
+				Image: pythonImage,
+				Cmd: []string{"pip", "download", "--only-binary=:all:", "--no-cache-dir", "--disable-pip-version-check",
+					"--quiet", "--index-url", p.config.pipIndexURL, "--dest", pythonWorkDir + "/" + pythonWheelsDir,
+					"-r", "requirements.txt"},
+				WorkingDir: pythonWorkDir,
+			},
+			HostConfig: p.hostConfig(p.binds(), p.config.downloadNetwork),
+		}
+		output, err := p.runPipStep(download, "download")
+		if err != nil {
+			return output, err
+		}
+		downloadOutput = output
+		source = []string{"--no-index", "--find-links", pythonWorkDir + "/" + pythonWheelsDir}
+	}
+
+	install := docker.CreateContainerOptions{
+		Name: randomString(20),
+		Config: &docker.Config{
+			Image: pythonImage,
+			Cmd: append(append([]string{"pip", "install", "--no-cache-dir", "--disable-pip-version-check",
+				"--quiet", "--target", pythonWorkDir + "/" + pythonDepsDir}, source...), "-r", "requirements.txt"),
+			WorkingDir: pythonWorkDir,
+		},
+		HostConfig: p.hostConfig(p.binds(), p.config.buildNetwork),
+	}
+	output, err := p.runPipStep(install, "install")
+	return downloadOutput + output, err
+}
+
+// runPipStep runs the container that downloads or installs the requirements.
+func (p *PythonExecutor) runPipStep(option docker.CreateContainerOptions, step string) (string, error) {
+	output, code, err := p.runToCompletion(option, pythonBuildTimeout)
+	if err != nil {
+		return "", fmt.Errorf("failed to %v requirements: %v", step, err)
+	} else if code != 0 {
+		return output, fmt.Errorf("failed to %v requirements: exit code %v\n%v", step, code, truncateOutput(output))
+	}
+	return output, nil
+}
+
+func (p *PythonExecutor) archive() []byte {
+	return p.PackageArchive
+}
+
+func (p *PythonExecutor) images() []string {
+	return []string{pythonImage}
+}
+
+func (p *PythonExecutor) binds() []string {
+	return []string{
+		fmt.Sprintf("%v:%v", p.projectDir, pythonWorkDir),
+	}
+}
+
+// prepare builds the submitted project if it's not built yet and returns the options
+// of the container running it.
+func (p *PythonExecutor) prepare(args []string) (docker.CreateContainerOptions, error) {
+	if _, err := p.Build(); err != nil {
+		return docker.CreateContainerOptions{}, err
+	}
+	entrypoint, err := p.entrypoint()
+	if err != nil {
+		return docker.CreateContainerOptions{}, err
+	}
+	p.workDir = pythonWorkDir
+
+	option := docker.CreateContainerOptions{
+		Name: randomString(20),
+		Config: &docker.Config{
+			Image: pythonImage,
+			Cmd:   append([]string{"python", entrypoint}, args...),
+			Env: []string{
+				"PYTHONPATH=" + pythonWorkDir + "/" + pythonDepsDir,
+				// Interactive tests expect the output as soon as it's printed.
+				"PYTHONUNBUFFERED=1",
+			},
+			WorkingDir: pythonWorkDir,
+		},
+		HostConfig: p.hostConfig(p.binds(), p.config.network),
+	}
+	p.exposePorts(option.Config)
+	return option, nil
+}
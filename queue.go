@@ -0,0 +1,95 @@
+package godge
+
+import (
+	"errors"
+	"sync"
+)
+
+var errQueueFull = errors.New("the submission queue is full, try again later")
+
+// submissionQueue is a bounded queue of pending submissions. Submissions are
+// dequeued in a round robin fashion across users, and each user can only have a
+// limited number of pending submissions, so a single user submitting a lot can't
+// starve the others.
+type submissionQueue struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	capacity int
+	// The maximum number of pending submissions of a single user.
+	userCapacity int
+	size         int
+	// The users with pending submissions, in the order they'll be served.
+	users   []string
+	pending map[string][]submissionRequest
+}
+
+func newSubmissionQueue(capacity, userCapacity int) *submissionQueue {
+	q := &submissionQueue{
+		capacity:     capacity,
+		userCapacity: userCapacity,
+		pending:      make(map[string][]submissionRequest),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds a new submission request to the queue. It returns errQueueFull if
+// the queue or the submitter's share of it reached its capacity.
+func (q *submissionQueue) push(sreq submissionRequest) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	u := sreq.submission.Username
+	if q.capacity > 0 && q.size >= q.capacity {
+		return errQueueFull
+	}
+	if q.userCapacity > 0 && len(q.pending[u]) >= q.userCapacity {
+		return errQueueFull
+	}
+	if len(q.pending[u]) == 0 {
+		q.users = append(q.users, u)
+	}
+	q.pending[u] = append(q.pending[u], sreq)
+	q.size++
+	q.cond.Signal()
+	return nil
+}
+
+// pop blocks until there's a pending submission and returns the oldest
+// submission of the next user in turn.
+func (q *submissionQueue) pop() submissionRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for q.size == 0 {
+		q.cond.Wait()
+	}
+	u := q.users[0]
+	q.users = q.users[1:]
+	sreq := q.pending[u][0]
+	q.pending[u] = q.pending[u][1:]
+	if len(q.pending[u]) == 0 {
+		delete(q.pending, u)
+	} else {
+		// The user still has pending submissions, put them back at the end of the line.
+		q.users = append(q.users, u)
+	}
+	q.size--
+	return sreq
+}
+
+// positions returns the 1-based position of each pending submission, keyed by
+// the submission's id, in the order they'll be dequeued.
+func (q *submissionQueue) positions() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ret := make(map[string]int, q.size)
+	pos := 1
+	for round := 0; pos <= q.size; round++ {
+		for _, u := range q.users {
+			if round < len(q.pending[u]) {
+				ret[q.pending[u][round].submission.id] = pos
+				pos++
+			}
+		}
+	}
+	return ret
+}
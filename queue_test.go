@@ -0,0 +1,76 @@
+package godge
+
+import (
+	"reflect"
+	"testing"
+)
+
+func queuedSubmission(id, username string) submissionRequest {
+	return submissionRequest{submission: &Submission{id: id, Username: username}}
+}
+
+func TestSubmissionQueueRoundRobin(t *testing.T) {
+	q := newSubmissionQueue(0, 0)
+	for _, s := range []struct{ id, user string }{
+		{"a1", "alice"}, {"a2", "alice"}, {"a3", "alice"},
+		{"b1", "bob"},
+		{"c1", "carol"}, {"c2", "carol"},
+	} {
+		if err := q.push(queuedSubmission(s.id, s.user)); err != nil {
+			t.Fatalf("push(%v) = %v, want nil", s.id, err)
+		}
+	}
+
+	want := []string{"a1", "b1", "c1", "a2", "c2", "a3"}
+	wantPositions := make(map[string]int)
+	for i, id := range want {
+		wantPositions[id] = i + 1
+	}
+	if got := q.positions(); !reflect.DeepEqual(got, wantPositions) {
+		t.Errorf("positions() = %v, want %v", got, wantPositions)
+	}
+
+	var got []string
+	for range want {
+		got = append(got, q.pop().submission.id)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pop order = %v, want %v", got, want)
+	}
+}
+
+func TestSubmissionQueueUserCapacity(t *testing.T) {
+	q := newSubmissionQueue(10, 2)
+	for _, id := range []string{"a1", "a2"} {
+		if err := q.push(queuedSubmission(id, "alice")); err != nil {
+			t.Fatalf("push(%v) = %v, want nil", id, err)
+		}
+	}
+	if err := q.push(queuedSubmission("a3", "alice")); err != errQueueFull {
+		t.Errorf("push over the user's capacity = %v, want %v", err, errQueueFull)
+	}
+	// The other users are not affected by alice's submissions.
+	if err := q.push(queuedSubmission("b1", "bob")); err != nil {
+		t.Errorf("push of another user = %v, want nil", err)
+	}
+
+	// Alice can submit again once one of her submissions is dequeued.
+	if id := q.pop().submission.id; id != "a1" {
+		t.Fatalf("pop() = %v, want a1", id)
+	}
+	if err := q.push(queuedSubmission("a3", "alice")); err != nil {
+		t.Errorf("push after a pop = %v, want nil", err)
+	}
+}
+
+func TestSubmissionQueueCapacity(t *testing.T) {
+	q := newSubmissionQueue(2, 0)
+	for _, s := range []struct{ id, user string }{{"a1", "alice"}, {"b1", "bob"}} {
+		if err := q.push(queuedSubmission(s.id, s.user)); err != nil {
+			t.Fatalf("push(%v) = %v, want nil", s.id, err)
+		}
+	}
+	if err := q.push(queuedSubmission("c1", "carol")); err != errQueueFull {
+		t.Errorf("push over the capacity = %v, want %v", err, errQueueFull)
+	}
+}
@@ -0,0 +1,73 @@
+package godge
+
+import (
+	"fmt"
+
+	docker "github.com/fsouza/go-dockerclient"
+)
+
+// Ulimit is a resource limit set using setrlimit in the submission's container
+// (e.g. "nofile" or "nproc").
+type Ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// ResourceLimits defines the resources available to the container running the
+// submission. Zero values mean no limit.
+type ResourceLimits struct {
+	// The memory limit in bytes. Swap is disabled when it's set. A submission that
+	// exceeds it gets a "Memory Limit Exceeded" verdict.
+	Memory int64
+	// The relative CPU weight of the container compared to other containers.
+	CPUShares int64
+	// The CPU time in microseconds the container can use every CPUPeriod.
+	CPUQuota int64
+	// The CPU period in microseconds (defaults to 100000 when CPUQuota is set).
+	CPUPeriod int64
+	// The maximum number of processes/threads in the container.
+	PidsLimit int64
+	// Extra resource limits of the processes in the container.
+	Ulimits []Ulimit
+	// If set, /tmp in the container is a tmpfs of the given size in bytes.
+	TmpfsSize int64
+}
+
+// DefaultResourceLimits are used for the tasks that don't define their own limits.
+var DefaultResourceLimits = ResourceLimits{
+	Memory:    512 * 1024 * 1024,
+	CPUQuota:  100000,
+	PidsLimit: 256,
+	Ulimits: []Ulimit{
+		{Name: "nofile", Soft: 1024, Hard: 1024},
+	},
+	TmpfsSize: 64 * 1024 * 1024,
+}
+
+// apply sets the limits on the container's host config.
+func (r ResourceLimits) apply(hc *docker.HostConfig) {
+	if r.Memory > 0 {
+		hc.Memory = r.Memory
+		hc.MemorySwap = r.Memory
+	}
+	hc.CPUShares = r.CPUShares
+	hc.CPUQuota = r.CPUQuota
+	hc.CPUPeriod = r.CPUPeriod
+	if r.CPUQuota > 0 && r.CPUPeriod == 0 {
+		hc.CPUPeriod = 100000
+	}
+	hc.PidsLimit = r.PidsLimit
+	for _, u := range r.Ulimits {
+		hc.Ulimits = append(hc.Ulimits, docker.ULimit{
+			Name: u.Name,
+			Soft: u.Soft,
+			Hard: u.Hard,
+		})
+	}
+	if r.TmpfsSize > 0 {
+		hc.Tmpfs = map[string]string{
+			"/tmp": fmt.Sprintf("rw,exec,size=%v", r.TmpfsSize),
+		}
+	}
+}
@@ -1,74 +1,231 @@
 package godge
 
 import (
-	"database/sql"
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
+// The scoring modes of the scoreboard.
 const (
-	failedVerdict = "Failed"
-	passedVerdict = "Passed"
+	// ScoringPoints ranks the users by the total of their best score of each task.
+	// It's the default.
+	ScoringPoints = "points"
+	// ScoringICPC ranks the users by the number of solved tasks, and then by their
+	// penalty time. The penalty of a solved task is the time from the start of the
+	// contest to its first accepted submission, plus the penalty of each rejected
+	// submission before it.
+	ScoringICPC = "icpc"
 )
 
-func saveToScoreboard(db *sqlx.DB, user, task string, verdict string) error {
-	_, err := db.Exec("INSERT INTO scoreboard (username, task_name, verdict, submitted_at) VALUES (?,?,?,?)", user, task, verdict, time.Now())
+// DefaultPenaltyPerAttempt is the default penalty of a rejected submission in ICPC
+// scoring.
+const DefaultPenaltyPerAttempt = 20 * time.Minute
+
+const (
+	failedVerdict              = "Failed"
+	passedVerdict              = "Passed"
+	timeLimitExceededVerdict   = "Time Limit Exceeded"
+	memoryLimitExceededVerdict = "Memory Limit Exceeded"
+	compilationErrorVerdict    = "Compilation Error"
+)
+
+func saveToScoreboard(db *sqlx.DB, contest, user, task string, verdict string, points int, submittedAt time.Time) error {
+	_, err := db.Exec("INSERT INTO scoreboard (contest, username, task_name, verdict, points, submitted_at) VALUES (?,?,?,?,?,?)", contest, user, task, verdict, points, submittedAt)
 	if err != nil {
 		return fmt.Errorf("failed to save scoreboard record: %v", err)
 	}
 	return nil
 }
 
-func getFromScoreboard(db *sqlx.DB, user, task string) (*string, error) {
-	var res string
-	err := db.Get(&res, "SELECT verdict FROM scoreboard WHERE username=? AND task_name=? ORDER BY ID DESC LIMIT 1", user, task)
-	if err == sql.ErrNoRows {
-		e := ""
-		return &e, nil
-	}
+// scoreboardEntry is a judged submission on the scoreboard.
+type scoreboardEntry struct {
+	Verdict     string    `db:"verdict"`
+	Points      int       `db:"points"`
+	SubmittedAt time.Time `db:"submitted_at"`
+}
+
+// getFromScoreboard returns the user's judged submissions of the task in the order
+// they were submitted. If before isn't zero, only the submissions submitted before
+// it are returned.
+func getFromScoreboard(db *sqlx.DB, contest, user, task string, before time.Time) ([]scoreboardEntry, error) {
+	var entries []scoreboardEntry
+	err := db.Select(&entries, "SELECT verdict, points, submitted_at FROM scoreboard WHERE contest=? AND username=? AND task_name=? ORDER BY submitted_at, id", contest, user, task)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get from scoreboard: %v", err)
 	}
-	return &res, nil
+	if before.IsZero() {
+		return entries, nil
+	}
+	var ret []scoreboardEntry
+	for _, e := range entries {
+		if e.SubmittedAt.Before(before) {
+			ret = append(ret, e)
+		}
+	}
+	return ret, nil
 }
 
-// returns a 2D array of the results (including the tasks as the first row and
-// the users as the first column). The results are rows are sorted by the score
-// of each user.
-func buildScoreboard(db *sqlx.DB, allUsers, allTasks []string) ([][]string, error) {
+// bestEntry returns the submission with the most points, or the latest one if none
+// of them earned points. It returns nil if there are no submissions.
+func bestEntry(entries []scoreboardEntry) *scoreboardEntry {
+	var best *scoreboardEntry
+	for i := range entries {
+		if best == nil || entries[i].Points >= best.Points {
+			best = &entries[i]
+		}
+	}
+	return best
+}
+
+// scoreboardCell formats the user's best submission for the task. The tasks with
+// points show the earned points, the others show the verdict.
+func scoreboardCell(t Task, e *scoreboardEntry) string {
+	switch {
+	case e == nil:
+		return ""
+	case t.weighted() && e.Points > 0:
+		return fmt.Sprintf("%v/%v", e.Points, t.maxPoints())
+	default:
+		return e.Verdict
+	}
+}
+
+// returns a 2D array of the results (including the tasks and the total as the first
+// row and the users as the first column). The rows are sorted by the total score of
+// each user, which is the sum of the best score of each task. Users with the same
+// score keep the order of allUsers. If before isn't zero, only the submissions made
+// before it are counted.
+func buildScoreboard(db *sqlx.DB, contest string, allUsers []string, allTasks []Task, before time.Time) ([][]string, error) {
 
 	var ret [][]string
+	totals := make(map[string]int)
 
 	for _, u := range allUsers {
 		row := []string{u}
 		for _, t := range allTasks {
-			v, err := getFromScoreboard(db, u, t)
+			entries, err := getFromScoreboard(db, contest, u, t.Name, before)
 			if err != nil {
 				return nil, fmt.Errorf("failed to build scoreboard: %v", err)
 			}
-			row = append(row, *v)
+			e := bestEntry(entries)
+			if e != nil {
+				totals[u] += e.Points
+			}
+			row = append(row, scoreboardCell(t, e))
 		}
+		row = append(row, strconv.Itoa(totals[u]))
 		ret = append(ret, row)
 	}
 
-	score := func(row []string) int {
-		var sc int
-		for _, r := range row {
-			if r == passedVerdict {
-				sc++
+	sort.SliceStable(ret, func(i, j int) bool {
+		return totals[ret[i][0]] > totals[ret[j][0]]
+	})
+
+	header := []string{""}
+	for _, t := range allTasks {
+		header = append(header, t.Name)
+	}
+	header = append(header, "Total")
+	ret = append([][]string{header}, ret...)
+
+	return ret, nil
+}
+
+// icpcResult is the result of a user in a task in ICPC scoring.
+type icpcResult struct {
+	// The number of counted submissions, up to and including the first accepted one.
+	attempts int
+	solved   bool
+	// The time from the start of the contest to the first accepted submission.
+	solvedAfter time.Duration
+}
+
+// penalty returns the penalty time of the result, zero if the task isn't solved.
+func (r icpcResult) penalty(penaltyPerAttempt time.Duration) time.Duration {
+	if !r.solved {
+		return 0
+	}
+	return r.solvedAfter.Truncate(time.Minute) + time.Duration(r.attempts-1)*penaltyPerAttempt
+}
+
+func (r icpcResult) String() string {
+	attempts := fmt.Sprintf("%v attempts", r.attempts)
+	if r.attempts == 1 {
+		attempts = "1 attempt"
+	}
+	switch {
+	case r.solved:
+		return fmt.Sprintf("%v min (%v)", int(r.solvedAfter/time.Minute), attempts)
+	case r.attempts > 0:
+		return attempts
+	default:
+		return ""
+	}
+}
+
+// icpcResultOf returns the ICPC result of the user's submissions for a task.
+// Compilation errors aren't counted as attempts.
+func icpcResultOf(entries []scoreboardEntry, start time.Time) icpcResult {
+	var res icpcResult
+	for _, e := range entries {
+		if e.Verdict == compilationErrorVerdict {
+			continue
+		}
+		res.attempts++
+		if e.Verdict == passedVerdict {
+			res.solved = true
+			if e.SubmittedAt.After(start) {
+				res.solvedAfter = e.SubmittedAt.Sub(start)
 			}
+			break
 		}
-		return sc
 	}
+	return res
+}
+
+// buildICPCScoreboard is buildScoreboard for ICPC scoring. The last two columns
+// are the number of solved tasks and the total penalty in minutes.
+func buildICPCScoreboard(db *sqlx.DB, contest string, allUsers []string, allTasks []Task, before, start time.Time, penaltyPerAttempt time.Duration) ([][]string, error) {
+
+	var ret [][]string
+	solved := make(map[string]int)
+	penalty := make(map[string]time.Duration)
 
-	sort.Slice(ret, func(i, j int) bool {
-		return score(ret[i][1:]) > score(ret[j][1:])
+	for _, u := range allUsers {
+		row := []string{u}
+		for _, t := range allTasks {
+			entries, err := getFromScoreboard(db, contest, u, t.Name, before)
+			if err != nil {
+				return nil, fmt.Errorf("failed to build scoreboard: %v", err)
+			}
+			r := icpcResultOf(entries, start)
+			if r.solved {
+				solved[u]++
+				penalty[u] += r.penalty(penaltyPerAttempt)
+			}
+			row = append(row, r.String())
+		}
+		row = append(row, strconv.Itoa(solved[u]), strconv.Itoa(int(penalty[u]/time.Minute)))
+		ret = append(ret, row)
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		ui, uj := ret[i][0], ret[j][0]
+		if solved[ui] != solved[uj] {
+			return solved[ui] > solved[uj]
+		}
+		return penalty[ui] < penalty[uj]
 	})
 
-	header := append([]string{}, append([]string{""}, allTasks...)...)
+	header := []string{""}
+	for _, t := range allTasks {
+		header = append(header, t.Name)
+	}
+	header = append(header, "Solved", "Penalty")
 	ret = append([][]string{header}, ret...)
 
 	return ret, nil
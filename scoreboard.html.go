@@ -19,7 +19,10 @@ var scoreboardTmpl = template.Must(template.New("scoreboard").Parse(`
 	</head>
 
 	<body>
-		<h1>Scoreboard!</h1>
+		<h1>{{ if $.Contest }}{{ $.Contest }} {{ end }}Scoreboard!</h1>
+		{{ if $.FrozenAt }}
+			<p>The scoreboard is frozen since {{ $.FrozenAt }}.</p>
+		{{ end }}
 		<table>
 			<tbody>
 				{{ range $i1, $row1 :=  $.Scoreboard }}
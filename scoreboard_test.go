@@ -0,0 +1,314 @@
+package godge
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// newTestDB returns an in-memory database with the judge's schema.
+func newTestDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	// Each connection has its own in-memory database.
+	db.SetMaxOpenConns(1)
+	s := &Server{db: db}
+	if err := s.initDB(); err != nil {
+		t.Fatalf("failed to init the database: %v", err)
+	}
+	return db
+}
+
+func TestICPCResult(t *testing.T) {
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+	tests := []struct {
+		desc        string
+		entries     []scoreboardEntry
+		want        icpcResult
+		wantPenalty time.Duration
+	}{
+		{
+			desc: "no submissions",
+		},
+		{
+			desc:        "the minutes are truncated",
+			entries:     []scoreboardEntry{{Verdict: passedVerdict, SubmittedAt: at(90 * time.Second)}},
+			want:        icpcResult{attempts: 1, solved: true, solvedAfter: 90 * time.Second},
+			wantPenalty: time.Minute,
+		},
+		{
+			desc: "only the attempts before the first accept are counted",
+			entries: []scoreboardEntry{
+				{Verdict: failedVerdict, SubmittedAt: at(time.Minute)},
+				{Verdict: timeLimitExceededVerdict, SubmittedAt: at(5 * time.Minute)},
+				{Verdict: passedVerdict, SubmittedAt: at(10*time.Minute + 59*time.Second)},
+				{Verdict: failedVerdict, SubmittedAt: at(12 * time.Minute)},
+				{Verdict: passedVerdict, SubmittedAt: at(13 * time.Minute)},
+			},
+			want:        icpcResult{attempts: 3, solved: true, solvedAfter: 10*time.Minute + 59*time.Second},
+			wantPenalty: 10*time.Minute + 2*DefaultPenaltyPerAttempt,
+		},
+		{
+			desc: "compilation errors aren't attempts",
+			entries: []scoreboardEntry{
+				{Verdict: compilationErrorVerdict, SubmittedAt: at(time.Minute)},
+				{Verdict: compilationErrorVerdict, SubmittedAt: at(2 * time.Minute)},
+				{Verdict: passedVerdict, SubmittedAt: at(5 * time.Minute)},
+			},
+			want:        icpcResult{attempts: 1, solved: true, solvedAfter: 5 * time.Minute},
+			wantPenalty: 5 * time.Minute,
+		},
+		{
+			desc: "unsolved tasks have no penalty",
+			entries: []scoreboardEntry{
+				{Verdict: failedVerdict, SubmittedAt: at(time.Minute)},
+				{Verdict: compilationErrorVerdict, SubmittedAt: at(2 * time.Minute)},
+				{Verdict: memoryLimitExceededVerdict, SubmittedAt: at(3 * time.Minute)},
+			},
+			want: icpcResult{attempts: 2},
+		},
+		{
+			desc:        "submitted before the start",
+			entries:     []scoreboardEntry{{Verdict: passedVerdict, SubmittedAt: at(-time.Minute)}},
+			want:        icpcResult{attempts: 1, solved: true},
+			wantPenalty: 0,
+		},
+	}
+	for _, test := range tests {
+		got := icpcResultOf(test.entries, start)
+		if got != test.want {
+			t.Errorf("%v: icpcResultOf() = %+v, want %+v", test.desc, got, test.want)
+		}
+		if penalty := got.penalty(DefaultPenaltyPerAttempt); penalty != test.wantPenalty {
+			t.Errorf("%v: penalty() = %v, want %v", test.desc, penalty, test.wantPenalty)
+		}
+	}
+}
+
+func TestBuildICPCScoreboard(t *testing.T) {
+	db := newTestDB(t)
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	for _, s := range []struct {
+		user, task, verdict string
+		after               time.Duration
+	}{
+		// Solved after 30 minutes and a rejected attempt.
+		{"alice", "A", failedVerdict, 10 * time.Minute},
+		{"alice", "A", passedVerdict, 30 * time.Minute},
+		// Solved after 40 minutes, ranked above alice for the lower penalty.
+		{"bob", "A", compilationErrorVerdict, 5 * time.Minute},
+		{"bob", "A", passedVerdict, 40 * time.Minute},
+		// Solved more tasks, ranked first despite the higher penalty.
+		{"carol", "A", passedVerdict, 50 * time.Minute},
+		{"carol", "B", passedVerdict, 55 * time.Minute},
+		{"dave", "B", failedVerdict, 20 * time.Minute},
+	} {
+		if err := saveToScoreboard(db, "", s.user, s.task, s.verdict, 0, start.Add(s.after)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tasks := []Task{{Name: "A"}, {Name: "B"}}
+	got, err := buildICPCScoreboard(db, "", []string{"dave", "alice", "bob", "carol"}, tasks, time.Time{}, start, DefaultPenaltyPerAttempt)
+	if err != nil {
+		t.Fatalf("buildICPCScoreboard() = %v, want nil", err)
+	}
+	want := [][]string{
+		{"", "A", "B", "Solved", "Penalty"},
+		{"carol", "50 min (1 attempt)", "55 min (1 attempt)", "2", "105"},
+		{"bob", "40 min (1 attempt)", "", "1", "40"},
+		{"alice", "30 min (2 attempts)", "", "1", "50"},
+		{"dave", "", "1 attempt", "0", "0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildICPCScoreboard() = %v, want %v", got, want)
+	}
+}
+
+func TestTaskPoints(t *testing.T) {
+	unweighted := Task{Tests: []Test{{Name: "a"}, {Name: "b"}}}
+	weighted := Task{Tests: []Test{{Name: "a", Points: 2}, {Name: "b", Points: 3}}}
+	failed := errors.New("test a failed")
+	tests := []struct {
+		desc    string
+		task    Task
+		results []TestResult
+		err     error
+		want    int
+	}{
+		{desc: "unweighted passed", task: unweighted, results: []TestResult{{}, {}}, want: 1},
+		{desc: "unweighted failed", task: unweighted, results: []TestResult{{}, {}}, err: failed, want: 0},
+		{desc: "weighted passed", task: weighted, results: []TestResult{{Points: 2}, {Points: 3}}, want: 5},
+		{desc: "weighted partially passed", task: weighted, results: []TestResult{{Points: 0}, {Points: 3}}, err: failed, want: 3},
+		{desc: "weighted failed", task: weighted, results: []TestResult{{}, {}}, err: failed, want: 0},
+	}
+	for _, test := range tests {
+		if got := test.task.points(test.results, test.err); got != test.want {
+			t.Errorf("%v: points() = %v, want %v", test.desc, got, test.want)
+		}
+	}
+	if got := unweighted.maxPoints(); got != 1 {
+		t.Errorf("maxPoints() of an unweighted task = %v, want 1", got)
+	}
+	if got := weighted.maxPoints(); got != 5 {
+		t.Errorf("maxPoints() of a weighted task = %v, want 5", got)
+	}
+}
+
+func TestBestEntry(t *testing.T) {
+	at := func(min int) time.Time { return time.Date(2026, 1, 1, 10, min, 0, 0, time.UTC) }
+	tests := []struct {
+		desc    string
+		entries []scoreboardEntry
+		want    *scoreboardEntry
+	}{
+		{desc: "no submissions"},
+		{
+			desc: "the most points",
+			entries: []scoreboardEntry{
+				{Verdict: failedVerdict, Points: 2, SubmittedAt: at(1)},
+				{Verdict: passedVerdict, Points: 5, SubmittedAt: at(2)},
+				{Verdict: failedVerdict, Points: 3, SubmittedAt: at(3)},
+			},
+			want: &scoreboardEntry{Verdict: passedVerdict, Points: 5, SubmittedAt: at(2)},
+		},
+		{
+			desc: "ties go to the latest submission",
+			entries: []scoreboardEntry{
+				{Verdict: failedVerdict, Points: 3, SubmittedAt: at(1)},
+				{Verdict: timeLimitExceededVerdict, Points: 3, SubmittedAt: at(2)},
+				{Verdict: failedVerdict, Points: 1, SubmittedAt: at(3)},
+			},
+			want: &scoreboardEntry{Verdict: timeLimitExceededVerdict, Points: 3, SubmittedAt: at(2)},
+		},
+		{
+			desc: "no points",
+			entries: []scoreboardEntry{
+				{Verdict: failedVerdict, SubmittedAt: at(1)},
+				{Verdict: compilationErrorVerdict, SubmittedAt: at(2)},
+			},
+			want: &scoreboardEntry{Verdict: compilationErrorVerdict, SubmittedAt: at(2)},
+		},
+	}
+	for _, test := range tests {
+		if got := bestEntry(test.entries); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: bestEntry() = %+v, want %+v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestGetFromScoreboardBefore(t *testing.T) {
+	db := newTestDB(t)
+	at := func(min int) time.Time { return time.Date(2026, 1, 1, 10, min, 0, 0, time.UTC) }
+	// Saved in the order they're judged, which isn't the order they're submitted in.
+	for _, e := range []scoreboardEntry{
+		{Verdict: failedVerdict, SubmittedAt: at(10)},
+		{Verdict: passedVerdict, Points: 1, SubmittedAt: at(30)},
+		{Verdict: failedVerdict, SubmittedAt: at(5)},
+		{Verdict: failedVerdict, SubmittedAt: at(20)},
+	} {
+		if err := saveToScoreboard(db, "", "alice", "A", e.Verdict, e.Points, e.SubmittedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another user and another contest.
+	if err := saveToScoreboard(db, "", "bob", "A", passedVerdict, 1, at(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveToScoreboard(db, "other", "alice", "A", passedVerdict, 1, at(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	submittedAt := func(entries []scoreboardEntry) []time.Time {
+		var ret []time.Time
+		for _, e := range entries {
+			ret = append(ret, e.SubmittedAt.UTC())
+		}
+		return ret
+	}
+	tests := []struct {
+		desc   string
+		before time.Time
+		want   []time.Time
+	}{
+		{desc: "not frozen", want: []time.Time{at(5), at(10), at(20), at(30)}},
+		{desc: "frozen", before: at(20), want: []time.Time{at(5), at(10)}},
+		{desc: "frozen before all of them", before: at(5)},
+	}
+	for _, test := range tests {
+		entries, err := getFromScoreboard(db, "", "alice", "A", test.before)
+		if err != nil {
+			t.Fatalf("%v: getFromScoreboard() = %v, want nil", test.desc, err)
+		}
+		if got := submittedAt(entries); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: getFromScoreboard() submitted at %v, want %v", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestBuildScoreboard(t *testing.T) {
+	db := newTestDB(t)
+	at := func(min int) time.Time { return time.Date(2026, 1, 1, 10, min, 0, 0, time.UTC) }
+	for _, s := range []struct {
+		user, task, verdict string
+		points              int
+		min                 int
+	}{
+		{"alice", "Weighted", failedVerdict, 3, 1},
+		{"alice", "Unweighted", passedVerdict, 1, 2},
+		{"bob", "Weighted", failedVerdict, 2, 1},
+		// Submitted during the freeze.
+		{"bob", "Weighted", passedVerdict, 5, 40},
+		{"carol", "Unweighted", failedVerdict, 0, 3},
+	} {
+		if err := saveToScoreboard(db, "", s.user, s.task, s.verdict, s.points, at(s.min)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tasks := []Task{
+		{Name: "Weighted", Tests: []Test{{Name: "a", Points: 2}, {Name: "b", Points: 3}}},
+		{Name: "Unweighted", Tests: []Test{{Name: "a"}}},
+	}
+	users := []string{"carol", "bob", "alice"}
+
+	tests := []struct {
+		desc   string
+		before time.Time
+		want   [][]string
+	}{
+		{
+			desc: "not frozen",
+			want: [][]string{
+				{"", "Weighted", "Unweighted", "Total"},
+				{"bob", "5/5", "", "5"},
+				{"alice", "3/5", "Passed", "4"},
+				{"carol", "", "Failed", "0"},
+			},
+		},
+		{
+			desc:   "frozen",
+			before: at(30),
+			want: [][]string{
+				{"", "Weighted", "Unweighted", "Total"},
+				{"alice", "3/5", "Passed", "4"},
+				{"bob", "2/5", "", "2"},
+				{"carol", "", "Failed", "0"},
+			},
+		},
+	}
+	for _, test := range tests {
+		got, err := buildScoreboard(db, "", users, tasks, test.before)
+		if err != nil {
+			t.Fatalf("%v: buildScoreboard() = %v, want nil", test.desc, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: buildScoreboard() = %v, want %v", test.desc, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package godge
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// The modes of the self-check that runs the tasks' solutions when the server starts.
+const (
+	// SelfCheckOff skips the self-check on startup. It can still be run on demand.
+	SelfCheckOff = "off"
+	// SelfCheckWarn logs the solutions that don't behave as expected. It's the default.
+	SelfCheckWarn = "warn"
+	// SelfCheckStrict refuses to start the server if a solution doesn't behave as
+	// expected.
+	SelfCheckStrict = "strict"
+)
+
+// The username that the solutions are submitted as.
+const selfCheckUsername = "godge-selfcheck"
+
+// Solution is a solution of a task that's known to be right or wrong. It's run
+// through the real executor by the server's self-check to catch broken tests.
+type Solution struct {
+	// Identifies the solution in the self-check's report (e.g. "off by one").
+	Name string
+	// The language of the solution, which must be accepted by the task.
+	Language string
+	// The zip archive of the solution, in the same format as the command line client's
+	// submissions (e.g. the zipped "main" package of a Go solution). Python solutions
+	// run main.py.
+	Archive []byte
+}
+
+// SolutionCheck is the result of checking one of the task's solutions. It's exposed
+// to be used by the command line client.
+type SolutionCheck struct {
+	// The contest of the task, empty for the default contest.
+	Contest  string `json:"contest,omitempty"`
+	Task     string `json:"task"`
+	Solution string `json:"solution"`
+	// Whether it's the reference solution, which must pass all the tests, or a known
+	// bad one, which must fail at least one of them.
+	Reference bool `json:"reference"`
+	// Whether the solution behaved as expected.
+	OK bool `json:"ok"`
+	// The error of the solution's submission, if any.
+	Error string       `json:"error,omitempty"`
+	Tests []TestResult `json:"tests,omitempty"`
+}
+
+// validate checks that the solution can be submitted to the task.
+func (sol Solution) validate(t Task, limits ArchiveLimits) error {
+	if !t.acceptsLanguage(sol.Language) {
+		return fmt.Errorf("the task doesn't accept %v submissions", sol.Language)
+	}
+	if _, err := newExecutor(sol.Language); err != nil {
+		return err
+	}
+	if err := validateArchive(sol.Archive, limits); err != nil {
+		return fmt.Errorf("invalid archive: %v", err)
+	}
+	return nil
+}
+
+// newSolutionSubmission creates a submission of the solution to the task. The
+// archive is passed to the language's executor the same way the command line client
+// does.
+func (s *Server) newSolutionSubmission(c *contest, t Task, sol Solution) (*Submission, error) {
+	e, err := newExecutor(sol.Language)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(struct {
+		PackageArchive []byte `json:"packageArchive"`
+	}{sol.Archive})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal archive: %v", err)
+	}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil, fmt.Errorf("failed to create executor: %v", err)
+	}
+	sub := &Submission{
+		id:       randomString(20),
+		Language: sol.Language,
+		Contest:  c.Name,
+		TaskName: t.Name,
+		Username: selfCheckUsername,
+		Executor: e,
+	}
+	if de, ok := e.(dockerExecutor); ok {
+		de.setDockerClient(s.dockerClient)
+		cfg := s.executorConfig(t, sol.Language)
+		cfg.submissionID = sub.id
+		de.setConfig(cfg)
+	}
+	return sub, nil
+}
+
+// checkSolution runs the tests of the task against the solution. The solution
+// doesn't go through the queue and doesn't affect the scoreboard.
+func (s *Server) checkSolution(c *contest, t Task, sol Solution, reference bool) SolutionCheck {
+	check := SolutionCheck{
+		Contest:   c.Name,
+		Task:      t.Name,
+		Solution:  sol.Name,
+		Reference: reference,
+	}
+	sub, err := s.newSolutionSubmission(c, t, sol)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	s.runningSubmissions.set(sub.id, sub)
+	results, err := t.execute(sub, nil)
+	s.runningSubmissions.del(sub.id)
+	if de, ok := sub.Executor.(dockerExecutor); ok {
+		de.cleanup()
+	}
+
+	check.Tests = results
+	if err != nil {
+		check.Error = err.Error()
+	}
+	if reference {
+		check.OK = err == nil
+	} else {
+		// A solution that fails before running the tests (e.g. doesn't compile) doesn't
+		// say anything about the tests.
+		for _, r := range results {
+			check.OK = check.OK || (r.Verdict != passedVerdict && r.Verdict != compilationErrorVerdict)
+		}
+	}
+	return check
+}
+
+// selfCheck checks the solutions of the contest's tasks, or of all its tasks if none
+// is given.
+func (s *Server) selfCheck(c *contest, names []string) ([]SolutionCheck, error) {
+	if len(names) == 0 {
+		names = c.tasks.names()
+		sort.Strings(names)
+	}
+	var checks []SolutionCheck
+	for _, name := range names {
+		t, ok := c.tasks.get(name)
+		if !ok {
+			return nil, fmt.Errorf("task %v not found", name)
+		}
+		if t.Reference != nil {
+			checks = append(checks, s.checkSolution(c, t, *t.Reference, true))
+		}
+		for _, sol := range t.BadSolutions {
+			checks = append(checks, s.checkSolution(c, t, sol, false))
+		}
+	}
+	return checks, nil
+}
+
+// logSelfCheck logs the result of the self-check and returns the failed checks.
+func logSelfCheck(checks []SolutionCheck) []SolutionCheck {
+	var failed []SolutionCheck
+	for _, c := range checks {
+		if c.OK {
+			log.Printf("self-check: %v solution %q of %v behaves as expected", solutionKind(c.Reference), c.Solution, c.task())
+			continue
+		}
+		failed = append(failed, c)
+		log.Printf("SELF-CHECK FAILED: %v", c.failure())
+	}
+	return failed
+}
+
+// failure describes how the solution didn't behave as expected.
+func (c SolutionCheck) failure() string {
+	if c.Reference {
+		return fmt.Sprintf("the reference solution %q of %v failed: %v", c.Solution, c.task(), c.Error)
+	}
+	if c.Error == "" {
+		return fmt.Sprintf("the bad solution %q of %v passed all the tests", c.Solution, c.task())
+	}
+	return fmt.Sprintf("the bad solution %q of %v didn't fail any test: %v", c.Solution, c.task(), c.Error)
+}
+
+// task describes the checked task, along with its contest if it's not the default one.
+func (c SolutionCheck) task() string {
+	if c.Contest == defaultContest {
+		return fmt.Sprintf("task %v", c.Task)
+	}
+	return fmt.Sprintf("task %v of contest %v", c.Task, c.Contest)
+}
+
+func solutionKind(reference bool) string {
+	if reference {
+		return "reference"
+	}
+	return "bad"
+}
+
+// Handles the self-check requests (/admin/selfcheck). The tasks to check are given
+// with the "task" query parameter, all the contest's tasks are checked if it's missing.
+func (s *Server) selfCheckHTTPHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		httpJSONError(w, "Only POST requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authenticateAdmin(req) {
+		httpJSONError(w, "Wrong admin token", http.StatusUnauthorized)
+		return
+	}
+	c, ok := s.requestContest(w, req)
+	if !ok {
+		return
+	}
+
+	checks, err := s.selfCheck(c, req.URL.Query()["task"])
+	if err != nil {
+		httpJSONError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	logSelfCheck(checks)
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(checks); err != nil {
+		httpJSONError(w, "Failed to encode self-check", http.StatusInternalServerError)
+		return
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"runtime"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
@@ -80,12 +83,87 @@ func (t *tasks) tasks() []Task {
 
 // Server holds all the information related to a single instance of the judge. It's used to register Tasks and start the HTTP server.
 type Server struct {
+	// The number of submissions that are judged in parallel. It defaults to the
+	// number of CPUs and must be set before calling Start.
+	Workers int
+	// The maximum number of submissions waiting to be judged. Submissions received
+	// while the queue is full are rejected. Zero means unbounded.
+	MaxQueuedSubmissions int
+	// The maximum number of submissions of a single user waiting to be judged, so
+	// that a user can't fill the whole queue. It defaults to 10. Zero means unbounded.
+	MaxQueuedSubmissionsPerUser int
+	// The networking mode of the containers building the submissions. It defaults to
+	// NetworkNone, as the builds run the submitted code (e.g. the steps of a Dockerfile
+	// or the setup.py of a Python dependency). The dependencies of the Go and Python
+	// submissions are downloaded beforehand (see DownloadNetwork), while the docker
+	// ones must be vendored or pre-fetched in the images. Set it to NetworkOpen, or the
+	// BuildNetwork of the tasks that need it, to let the builds download them.
+	BuildNetwork string
+	// The networking mode of the containers downloading the dependencies of the Go
+	// and Python submissions before they're built. The downloads only run the package
+	// managers, never the submitted code, and the Python ones are limited to wheels.
+	// It defaults to NetworkOpen. Set it to NetworkRestricted along with GoProxy and
+	// PipIndexURL to download from a proxy on the judge's internal network, or to
+	// NetworkNone to disable the downloads.
+	DownloadNetwork string
+	// The Go module proxy and the Python package index that the dependencies are
+	// downloaded from. They default to DefaultGoProxy and DefaultPipIndexURL.
+	GoProxy     string
+	PipIndexURL string
+	// The docker image used to build and run the Go submissions. It defaults to
+	// DefaultGoImage and can be overridden per task.
+	GoImage string
+	// The number of idle containers kept ready per language (e.g. {"go": 4}) to cut the
+	// latency of running the submissions. Containers are pooled per task, and the pool
+	// of a task is filled after its first run. The pooled containers run the submission
+	// using /bin/sh (ignoring the images' entrypoints), so the images must have it. The
+	// docker submissions built from a Dockerfile are never pooled, as each of them
+	// runs its own image.
+	WarmContainers map[string]int
+	// The dir under which the submissions are unzipped. It defaults to a "godge" dir in
+	// the system's temp dir. The submissions' dirs left behind by a previous run are
+	// removed on startup, so the dir must not be shared with other servers.
+	WorkDir string
+	// The limits of the submitted archives. It defaults to DefaultArchiveLimits.
+	ArchiveLimits ArchiveLimits
+	// The maximum size in bytes of a submission's request, which includes the base64
+	// encoded archive. It defaults to DefaultMaxSubmissionSize. Zero means unlimited.
+	MaxSubmissionSize int64
+	// How the tasks' solutions are checked when the server starts: SelfCheckWarn
+	// (the default), SelfCheckStrict or SelfCheckOff.
+	SelfCheck string
+	// How the scoreboard ranks the users: ScoringPoints (the default) or ScoringICPC.
+	// This and the following contest settings are the ones of the default contest,
+	// which holds the tasks registered with RegisterTask. The contests registered
+	// with RegisterContest have their own.
+	Scoring string
+	// The penalty of each rejected submission before the first accepted one in ICPC
+	// scoring. It defaults to DefaultPenaltyPerAttempt.
+	PenaltyPerAttempt time.Duration
+	// The start of the contest. Submissions are rejected before it, and the penalty
+	// time of ICPC scoring is counted from it. It defaults to the time the server
+	// starts, so set it if the server might be restarted during the contest.
+	ContestStart time.Time
+	// The end of the contest, after which submissions are rejected. Zero means that
+	// the contest doesn't end.
+	ContestEnd time.Time
+	// The public scoreboard stops updating for this duration before the end of the
+	// contest, while the users still get the verdicts of their own submissions. The
+	// scoreboard is revealed by an admin (see the unfreeze command of the client).
+	FreezeDuration time.Duration
+	// The token of the admin endpoints (e.g. the on demand self-check), sent as a
+	// bearer token. The admin endpoints are disabled if it's empty.
+	AdminToken string
+
 	address            string
-	tasks              tasks
-	pendingSubmissions chan submissionRequest
+	defaultContest     *contest
+	contests           contests
+	pendingSubmissions *submissionQueue
+	pool               *containerPool
 	requestErrorChan   chan error
 	dockerClient       *docker.Client
 	runningSubmissions runningSubmissions
+	events             submissionEvents
 	db                 *sqlx.DB
 }
 
@@ -106,71 +184,238 @@ func NewServer(address string, dockerAddress string, dbpath string) (*Server, er
 	}
 
 	return &Server{
-		address: address,
-		tasks: tasks{
-			m: make(map[string]Task),
+		Workers:                     runtime.NumCPU(),
+		MaxQueuedSubmissions:        100,
+		MaxQueuedSubmissionsPerUser: 10,
+		BuildNetwork:                NetworkNone,
+		DownloadNetwork:             NetworkOpen,
+		GoProxy:                     DefaultGoProxy,
+		PipIndexURL:                 DefaultPipIndexURL,
+		GoImage:                     DefaultGoImage,
+		WorkDir:                     defaultWorkDir,
+		ArchiveLimits:               DefaultArchiveLimits,
+		MaxSubmissionSize:           DefaultMaxSubmissionSize,
+		SelfCheck:                   SelfCheckWarn,
+		Scoring:                     ScoringPoints,
+		PenaltyPerAttempt:           DefaultPenaltyPerAttempt,
+		address:                     address,
+		defaultContest:              newContest(Contest{}),
+		contests: contests{
+			m: make(map[string]*contest),
 		},
-		pendingSubmissions: make(chan submissionRequest),
-		dockerClient:       dc,
+		dockerClient: dc,
 		runningSubmissions: runningSubmissions{
 			m: make(map[string]*Submission),
 		},
+		events: submissionEvents{
+			m: make(map[string]*eventStream),
+		},
 		db: db,
 	}, nil
 }
 
-// RegisterTask registers a new task in the server.
+// RegisterTask registers a new task in the server's default contest.
 func (s *Server) RegisterTask(t Task) {
-	s.tasks.set(t.Name, t)
+	s.defaultContest.tasks.set(t.Name, t)
+}
+
+// task returns the task of the submission's contest.
+func (s *Server) task(sub *Submission) (Task, bool) {
+	c, ok := s.contest(sub.Contest)
+	if !ok {
+		return Task{}, false
+	}
+	return c.tasks.get(sub.TaskName)
+}
+
+// executorConfig returns the settings of the executors running the task's submissions.
+// The network modes are validated when the server starts.
+func (s *Server) executorConfig(t Task, language string) executorConfig {
+	limits := DefaultResourceLimits
+	if t.Limits != nil {
+		limits = *t.Limits
+	}
+	network, _ := dockerNetworkMode(t.Network)
+	buildNetwork, _ := dockerNetworkMode(s.BuildNetwork)
+	if t.BuildNetwork != "" {
+		buildNetwork, _ = dockerNetworkMode(t.BuildNetwork)
+	}
+	downloadNetwork, _ := dockerNetworkMode(s.DownloadNetwork)
+	goImage := s.GoImage
+	if t.GoImage != "" {
+		goImage = t.GoImage
+	}
+	return executorConfig{
+		limits:          limits,
+		network:         network,
+		buildNetwork:    buildNetwork,
+		downloadNetwork: downloadNetwork,
+		goProxy:         s.GoProxy,
+		pipIndexURL:     s.PipIndexURL,
+		ports:           t.Ports,
+		docker:          t.Docker,
+		goImage:         goImage,
+		language:        language,
+		pool:            s.pool,
+		workDir:         s.WorkDir,
+		archiveLimits:   s.ArchiveLimits,
+	}
 }
 
 // handleSubmission is used to handle a received submission by executing the tests of the
-// submission's task against this submission.
-func (s *Server) handleSubmission(sub *Submission) error {
-	t, ok := s.tasks.get(sub.TaskName)
+// submission's task against this submission. It returns the result of each test.
+func (s *Server) handleSubmission(sub *Submission) ([]TestResult, error) {
+	t, ok := s.task(sub)
 	if !ok {
-		return fmt.Errorf("task %v not found", sub.TaskName)
+		return nil, fmt.Errorf("task %v not found", sub.TaskName)
 	}
 	s.runningSubmissions.set(sub.id, sub)
 	defer s.runningSubmissions.del(sub.id)
-	if err := t.execute(sub); err != nil {
-		return fmt.Errorf("task %v failed: %v", sub.TaskName, err)
+	progress := func(ev SubmissionEvent) {
+		s.events.publish(sub.id, ev)
 	}
-	return nil
+	results, err := t.execute(sub, progress)
+	if err != nil {
+		return results, fmt.Errorf("task %v failed: %v", sub.TaskName, err)
+	}
+	return results, nil
 }
 
 // A wrapper around the submission that's used for communication between
 // the http handler and the server.
 type submissionRequest struct {
-	result     chan error
+	record     *submissionRecord
 	submission *Submission
 }
 
-// Updates the scoreboard.
-func (s *Server) reportResult(sub *Submission, err error) {
+// Updates the scoreboard and the submission's status.
+func (s *Server) reportResult(sreq submissionRequest, results []TestResult, err error) {
+	sub := sreq.submission
 	log.Printf("%v submission for %v: %v", sub.Language, sub.TaskName, err)
+
+	sreq.record.Status = StatusDone
+	sreq.record.Passed = err == nil
+	sreq.record.Tests = results
+	sreq.record.Build = buildInfo{sub.build}
+	points := 0
+	if t, ok := s.task(sub); ok {
+		points = t.points(results, err)
+		sreq.record.Score, sreq.record.MaxScore = points, t.maxPoints()
+	}
 	if err != nil {
-		saveToScoreboard(s.db, sub.Username, sub.TaskName, failedVerdict)
-		return
+		sreq.record.Error = err.Error()
+	}
+	if err := sreq.record.update(s.db); err != nil {
+		log.Printf("failed to update submission %v: %v", sub.id, err)
 	}
-	saveToScoreboard(s.db, sub.Username, sub.TaskName, passedVerdict)
+	s.events.publish(sub.id, SubmissionEvent{
+		Type:   EventDone,
+		Result: sreq.record.response().Result,
+	})
+
+	saveToScoreboard(s.db, sub.Contest, sub.Username, sub.TaskName, submissionVerdict(sub, results, err), points, sreq.record.SubmittedAt)
 }
 
-// Executes the tests and report the result back to the http handler and the
-// scoreboard.
+// submissionVerdict returns the verdict of the whole submission, which is the
+// verdict of its first unsuccessful test.
+func submissionVerdict(sub *Submission, results []TestResult, err error) string {
+	if err == nil {
+		return passedVerdict
+	}
+	if de, ok := sub.Executor.(dockerExecutor); ok {
+		if _, failed := de.compilationError(); failed {
+			return compilationErrorVerdict
+		}
+	}
+	for _, r := range results {
+		if r.Verdict != passedVerdict {
+			return r.Verdict
+		}
+	}
+	return failedVerdict
+}
+
+// Executes the tests and report the result back to the scoreboard and the
+// submission's status. Multiple goroutines run processSubmissions in parallel.
 func (s *Server) processSubmissions() {
-	for sreq := range s.pendingSubmissions {
-		err := s.handleSubmission(sreq.submission)
-		sreq.result <- err
-		s.reportResult(sreq.submission, err)
+	for {
+		sreq := s.pendingSubmissions.pop()
+		s.publishQueuePositions()
+		sreq.record.Status = StatusRunning
+		if err := sreq.record.update(s.db); err != nil {
+			log.Printf("failed to update submission %v: %v", sreq.submission.id, err)
+		}
+		s.events.publish(sreq.submission.id, SubmissionEvent{Type: EventRunning})
+		results, err := s.handleSubmission(sreq.submission)
+		s.reportResult(sreq, results, err)
+		if de, ok := sreq.submission.Executor.(dockerExecutor); ok {
+			de.cleanup()
+		}
+	}
+}
+
+// publishQueuePositions notifies the queued submissions with their current position
+// in the queue.
+func (s *Server) publishQueuePositions() {
+	for id, pos := range s.pendingSubmissions.positions() {
+		s.events.publish(id, SubmissionEvent{Type: EventQueued, QueuePosition: pos})
 	}
 }
 
-// SubmissionResponse is the response returned back by the server in response
-// to the submission request. It's exposed to be used by the command line client.
+// SubmissionResponse is the result of a judged submission. It's exposed to be used
+// by the command line client.
 type SubmissionResponse struct {
 	Passed bool   `json:"passed"`
 	Error  string `json:"error"`
+	// The result of each of the task's tests, in order.
+	Tests []TestResult `json:"tests"`
+	// The info of the submission's build, if it was built.
+	Build *BuildInfo `json:"build,omitempty"`
+	// The points earned by the submission, out of the task's maximum.
+	Score    int `json:"score"`
+	MaxScore int `json:"maxScore"`
+}
+
+// DefaultMaxSubmissionSize is the default maximum size of a submission's request.
+const DefaultMaxSubmissionSize = 32 * 1024 * 1024
+
+// Limits are the limits of the submissions accepted by the server. It's exposed to
+// be used by the command line client.
+type Limits struct {
+	// The maximum size in bytes of a submission's request. Zero means unlimited.
+	MaxSubmissionSize int64 `json:"maxSubmissionSize"`
+	// The limits of the submitted archives.
+	Archive ArchiveLimits `json:"archive"`
+}
+
+// SubmitResponse is the response returned back by the server in response to the
+// submission request. The submission is judged asynchronously and its status can
+// be queried using the returned ID. It's exposed to be used by the command line client.
+type SubmitResponse struct {
+	ID string `json:"id"`
+}
+
+// SubmissionStatus is the response of the submission status requests. Result is
+// only set when the submission is done. It's exposed to be used by the command
+// line client.
+type SubmissionStatus struct {
+	ID       string              `json:"id"`
+	TaskName string              `json:"taskName"`
+	Status   string              `json:"status"`
+	Result   *SubmissionResponse `json:"result,omitempty"`
+}
+
+// authenticate checks the basic auth credentials of the request and returns
+// the username of the authenticated user.
+func (s *Server) authenticate(req *http.Request) (string, bool) {
+	username, password, ok := req.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	if u, err := userQ.find(s.db, username); err != nil || !u.isCorrectPassword(password) {
+		return "", false
+	}
+	return username, true
 }
 
 // The handler that handles submission requests.
@@ -179,54 +424,194 @@ func (s *Server) submitHTTPHandler(w http.ResponseWriter, req *http.Request) {
 		httpJSONError(w, "Only POST requests are allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	// Authenticate the user
-	username, password, ok := req.BasicAuth()
+	username, ok := s.authenticate(req)
 	if !ok {
 		httpJSONError(w, "Wrong username or password", http.StatusUnauthorized)
 		return
 	}
-	if u, err := userQ.find(s.db, username); err != nil || !u.isCorrectPassword(password) {
-		httpJSONError(w, "Wrong username or password", http.StatusUnauthorized)
-		return
+
+	if s.MaxSubmissionSize > 0 {
+		if req.ContentLength > s.MaxSubmissionSize {
+			httpJSONError(w, fmt.Sprintf("The submission (%v) is larger than the maximum of %v", formatBytes(req.ContentLength), formatBytes(s.MaxSubmissionSize)), http.StatusRequestEntityTooLarge)
+			return
+		}
+		req.Body = http.MaxBytesReader(w, req.Body, s.MaxSubmissionSize)
 	}
 
 	var sub Submission
 	err := json.NewDecoder(req.Body).Decode(&sub)
+	if err != nil && strings.Contains(err.Error(), "request body too large") {
+		httpJSONError(w, fmt.Sprintf("The submission is larger than the maximum of %v", formatBytes(s.MaxSubmissionSize)), http.StatusRequestEntityTooLarge)
+		return
+	}
 	if err != nil {
 		httpJSONError(w, fmt.Sprintf("Failed to decode request body: %v", err), http.StatusBadRequest)
 		return
 	}
-	sub.Executor.setDockerClient(s.dockerClient)
+	sub.Username = username
+	c, ok := s.contest(sub.Contest)
+	if !ok {
+		httpJSONError(w, fmt.Sprintf("Contest %v not found", sub.Contest), http.StatusNotFound)
+		return
+	}
+	joined, err := c.isParticipant(s.db, username)
+	if err != nil {
+		httpJSONError(w, fmt.Sprintf("Failed to fetch participants: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !joined {
+		httpJSONError(w, fmt.Sprintf("You haven't joined contest %v", c.Name), http.StatusForbidden)
+		return
+	}
+	if err := c.checkOpen(time.Now()); err != nil {
+		httpJSONError(w, fmt.Sprintf("Submissions are not accepted: %v", err), http.StatusForbidden)
+		return
+	}
+	t, ok := c.tasks.get(sub.TaskName)
+	if !ok {
+		httpJSONError(w, fmt.Sprintf("Task %v not found", sub.TaskName), http.StatusNotFound)
+		return
+	}
+	if !t.acceptsLanguage(sub.Language) {
+		httpJSONError(w, fmt.Sprintf("Task %v doesn't accept %v submissions, accepted languages: %v", t.Name, sub.Language, strings.Join(t.acceptedLanguages(), ", ")), http.StatusBadRequest)
+		return
+	}
+	if de, ok := sub.Executor.(dockerExecutor); ok {
+		if err := validateArchive(de.archive(), s.ArchiveLimits); err != nil {
+			httpJSONError(w, fmt.Sprintf("Rejected submission archive: %v", err), http.StatusBadRequest)
+			return
+		}
+		de.setDockerClient(s.dockerClient)
+		cfg := s.executorConfig(t, sub.Language)
+		cfg.submissionID = sub.id
+		de.setConfig(cfg)
+	}
+
+	record := &submissionRecord{
+		ID:          sub.id,
+		Contest:     sub.Contest,
+		Username:    sub.Username,
+		TaskName:    sub.TaskName,
+		Language:    sub.Language,
+		Status:      StatusQueued,
+		SubmittedAt: time.Now(),
+	}
+	if err := record.save(s.db); err != nil {
+		httpJSONError(w, fmt.Sprintf("Failed to save submission: %v", err), http.StatusInternalServerError)
+		return
+	}
 
 	// Send the submission for the server to run the tests.
-	res := make(chan error)
-	s.pendingSubmissions <- submissionRequest{
-		result:     res,
+	s.events.open(sub.id)
+	err = s.pendingSubmissions.push(submissionRequest{
+		record:     record,
 		submission: &sub,
+	})
+	if err != nil {
+		s.events.discard(sub.id)
+		record.delete(s.db)
+		httpJSONError(w, err.Error(), http.StatusServiceUnavailable)
+		return
 	}
+	s.publishQueuePositions()
 
-	// Wait for the submission results and prepare the response.
-	result := <-res
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(SubmitResponse{ID: sub.id}); err != nil {
+		httpJSONError(w, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
 
-	resp := SubmissionResponse{
-		Passed: true,
-		Error:  "",
+// Handles submission status queries (/submissions/{id}) and the submission events
+// streams (/submissions/{id}/events). Users can only query their own submissions.
+func (s *Server) submissionsHTTPHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		httpJSONError(w, "Only GET requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	username, ok := s.authenticate(req)
+	if !ok {
+		httpJSONError(w, "Wrong username or password", http.StatusUnauthorized)
+		return
 	}
 
-	if result != nil {
-		resp = SubmissionResponse{
-			Passed: false,
-			Error:  result.Error(),
-		}
+	id := strings.TrimPrefix(req.URL.Path, "/submissions/")
+	streamEvents := strings.HasSuffix(id, "/events")
+	id = strings.TrimSuffix(id, "/events")
+	record, err := submissionQ.find(s.db, id)
+	if err == sql.ErrNoRows || (err == nil && record.Username != username) {
+		httpJSONError(w, fmt.Sprintf("Submission %v not found", id), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		httpJSONError(w, fmt.Sprintf("Failed to fetch submission: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if streamEvents {
+		s.streamSubmissionEvents(w, req, record)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
+	if err := json.NewEncoder(w).Encode(record.response()); err != nil {
 		httpJSONError(w, "Failed to encode response", http.StatusInternalServerError)
 		return
 	}
 }
 
+// streamSubmissionEvents streams the events of the submission as server-sent
+// events until the submission is done or the client goes away.
+func (s *Server) streamSubmissionEvents(w http.ResponseWriter, req *http.Request, record *submissionRecord) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpJSONError(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	ch, ok := s.events.subscribe(record.ID)
+	if !ok {
+		// The submission is not being judged anymore, the stored status is all what we've got.
+		// It's fetched again as the submission might have finished after it was first fetched.
+		if r, err := submissionQ.find(s.db, record.ID); err == nil {
+			record = r
+		}
+		ch = make(chan SubmissionEvent, 1)
+		ch <- SubmissionEvent{Type: EventDone, Result: record.response().Result}
+		close(ch)
+	} else {
+		defer s.events.unsubscribe(record.ID, ch)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			b, err := json.Marshal(ev)
+			if err != nil {
+				log.Printf("failed to marshal event: %v", err)
+				return
+			}
+			fmt.Fprintf(w, "event: %v\ndata: %s\n\n", ev.Type, b)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
 // RegisterRequest represents the registeration request. It's exposed to be used by the
 // command line client.
 type RegisterRequest struct {
@@ -276,14 +661,18 @@ func (s *Server) registerHTTPHandler(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 }
 
-// Handles tasks queries.
+// Handles the tasks queries of a contest.
 func (s *Server) tasksHTTPHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodGet {
 		httpJSONError(w, "Only GET requests are allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	c, ok := s.requestContest(w, req)
+	if !ok {
+		return
+	}
 
-	ts := s.tasks.tasks()
+	ts := c.tasks.tasks()
 
 	w.WriteHeader(http.StatusOK)
 
@@ -293,34 +682,84 @@ func (s *Server) tasksHTTPHandler(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
-// Handles scoreboard requests.
+// Handles supported languages queries.
+func (s *Server) languagesHTTPHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		httpJSONError(w, "Only GET requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if err := json.NewEncoder(w).Encode(supportedLanguages()); err != nil {
+		httpJSONError(w, "Failed to encode languages", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Handles the requests of the submissions' limits.
+func (s *Server) limitsHTTPHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		httpJSONError(w, "Only GET requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	limits := Limits{
+		MaxSubmissionSize: s.MaxSubmissionSize,
+		Archive:           s.ArchiveLimits,
+	}
+	if err := json.NewEncoder(w).Encode(limits); err != nil {
+		httpJSONError(w, "Failed to encode limits", http.StatusInternalServerError)
+		return
+	}
+}
+
+// Handles the scoreboard requests of a contest.
 func (s *Server) scoreboardHTTPHandler(w http.ResponseWriter, req *http.Request) {
 	if req.Method != http.MethodGet {
 		httpJSONError(w, "Only GET requests are allowed", http.StatusMethodNotAllowed)
 		return
 	}
+	c, ok := s.requestContest(w, req)
+	if !ok {
+		return
+	}
 
 	w.Header().Add("Content-Type", "text/html")
 
-	ts := s.tasks.names()
-	sort.Strings(ts)
+	ts := c.tasks.tasks()
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Name < ts[j].Name })
 
-	us, err := userQ.usernames(s.db)
+	us, err := c.participants(s.db)
 	if err != nil {
 		httpJSONError(w, fmt.Sprintf("Failed to fetch users: %v", err), http.StatusInternalServerError)
 		return
 	}
 	sort.Strings(us)
 
-	scoreboard, err := buildScoreboard(s.db, us, ts)
+	// A frozen scoreboard only counts the submissions made before the freeze.
+	frozenAt, frozen := c.frozenAt(time.Now())
+	var scoreboard [][]string
+	if c.Scoring == ScoringICPC {
+		scoreboard, err = buildICPCScoreboard(s.db, c.Name, us, ts, frozenAt, c.Start, c.PenaltyPerAttempt)
+	} else {
+		scoreboard, err = buildScoreboard(s.db, c.Name, us, ts, frozenAt)
+	}
 	if err != nil {
 		httpJSONError(w, fmt.Sprintf("Failed to build scoreboard: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	scoreboardTmpl.Execute(w, map[string]interface{}{
+	data := map[string]interface{}{
+		"Contest":    c.Name,
 		"Scoreboard": scoreboard,
-	})
+	}
+	if frozen {
+		data["FrozenAt"] = frozenAt.Format("15:04")
+	}
+	scoreboardTmpl.Execute(w, data)
 }
 
 func (s *Server) proccessDockerEvents() {
@@ -334,17 +773,23 @@ func (s *Server) proccessDockerEvents() {
 		}
 
 		s.runningSubmissions.RLock()
-		for _, s := range s.runningSubmissions.m {
-			if s.Executor.containerID() == e.Actor.ID {
+		for _, sub := range s.runningSubmissions.m {
+			de, ok := sub.Executor.(dockerExecutor)
+			if ok && de.containerID() == e.Actor.ID {
 				switch e.Action {
 				case "start":
 					select {
-					case s.Executor.StartEvent() <- struct{}{}:
+					case sub.Executor.StartEvent() <- struct{}{}:
 					default:
 					}
 				case "die":
+					// Check for OOM kills before signaling the die event, so that
+					// the verdict is known by the time the test sees the event.
+					if c, err := s.dockerClient.InspectContainer(e.Actor.ID); err == nil && c.State.OOMKilled {
+						de.markOOMKilled()
+					}
 					select {
-					case s.Executor.DieEvent() <- struct{}{}:
+					case sub.Executor.DieEvent() <- struct{}{}:
 					default:
 					}
 				}
@@ -354,18 +799,143 @@ func (s *Server) proccessDockerEvents() {
 	}
 }
 
-// Start starts the http server and the goroutine responsible for processing
+// Start starts the http server and the goroutines responsible for processing
 // the submissions.
 func (s *Server) Start() error {
 	if err := s.initDB(); err != nil {
 		return fmt.Errorf("failed to init the database: %v", err)
 	}
-	go s.processSubmissions()
+	interrupted, err := submissionQ.failInterrupted(s.db)
+	if err != nil {
+		return fmt.Errorf("failed to update the interrupted submissions: %v", err)
+	}
+	if interrupted > 0 {
+		log.Printf("Marked %v submissions interrupted by the restart as failed", interrupted)
+	}
+	if s.Workers < 1 {
+		return fmt.Errorf("the number of workers must be positive, got %v", s.Workers)
+	}
+	if s.MaxQueuedSubmissionsPerUser < 0 {
+		return fmt.Errorf("the maximum number of queued submissions per user must not be negative, got %v", s.MaxQueuedSubmissionsPerUser)
+	}
+	if _, err := dockerNetworkMode(s.BuildNetwork); err != nil {
+		return fmt.Errorf("invalid build network: %v", err)
+	}
+	if _, err := dockerNetworkMode(s.DownloadNetwork); err != nil {
+		return fmt.Errorf("invalid download network: %v", err)
+	}
+	if s.GoProxy == "" || s.PipIndexURL == "" {
+		return fmt.Errorf("the Go proxy and the pip index must be set")
+	}
+	if s.GoImage == "" {
+		return fmt.Errorf("the Go image must be set")
+	}
+	if err := s.validateContests(); err != nil {
+		return err
+	}
+	for _, c := range s.allContests() {
+		if err := c.loadUnfrozen(s.db); err != nil {
+			return fmt.Errorf("failed to load the unfrozen scoreboards: %v", err)
+		}
+	}
+	for _, t := range s.allTasks() {
+		network, err := dockerNetworkMode(t.Network)
+		if err != nil {
+			return fmt.Errorf("invalid network of task %v: %v", t.Name, err)
+		}
+		if _, err := dockerNetworkMode(t.BuildNetwork); err != nil {
+			return fmt.Errorf("invalid build network of task %v: %v", t.Name, err)
+		}
+		for _, test := range t.Tests {
+			if test.Points < 0 {
+				return fmt.Errorf("test %v of task %v has negative points", test.Name, t.Name)
+			}
+		}
+		if len(t.Ports) > 0 && network == "none" {
+			return fmt.Errorf("task %v exposes ports but has networking disabled", t.Name)
+		}
+		for _, l := range t.Languages {
+			if _, err := newExecutor(l); err != nil {
+				return fmt.Errorf("task %v accepts an %v", t.Name, err)
+			}
+			if l == dockerLanguage && t.Docker == nil {
+				return fmt.Errorf("task %v accepts docker submissions but has no docker config", t.Name)
+			}
+		}
+		if t.Docker != nil {
+			if err := t.Docker.validate(); err != nil {
+				return fmt.Errorf("invalid docker config of task %v: %v", t.Name, err)
+			}
+		}
+		if t.Reference != nil {
+			if err := t.Reference.validate(t, s.ArchiveLimits); err != nil {
+				return fmt.Errorf("invalid reference solution of task %v: %v", t.Name, err)
+			}
+		}
+		for _, sol := range t.BadSolutions {
+			if err := sol.validate(t, s.ArchiveLimits); err != nil {
+				return fmt.Errorf("invalid bad solution %q of task %v: %v", sol.Name, t.Name, err)
+			}
+		}
+	}
+	switch s.SelfCheck {
+	case SelfCheckOff, SelfCheckWarn, SelfCheckStrict:
+	default:
+		return fmt.Errorf("unknown self-check mode %q", s.SelfCheck)
+	}
+	if err := ensureRestrictedNetwork(s.dockerClient); err != nil {
+		return err
+	}
+	if s.WorkDir == "" {
+		return fmt.Errorf("the work dir must be set")
+	}
+	if err := sweepOrphans(s.dockerClient, s.WorkDir); err != nil {
+		return fmt.Errorf("failed to remove orphans: %v", err)
+	}
+	if err := pullImages(s.dockerClient, s.requiredImages()); err != nil {
+		return err
+	}
+	for l, n := range s.WarmContainers {
+		if _, err := newExecutor(l); err != nil {
+			return fmt.Errorf("invalid warm containers: %v", err)
+		}
+		if n < 0 {
+			return fmt.Errorf("the number of warm %v containers must not be negative, got %v", l, n)
+		}
+	}
+	if len(s.WarmContainers) > 0 {
+		s.pool = newContainerPool(s.dockerClient, s.WarmContainers)
+	}
+	s.pendingSubmissions = newSubmissionQueue(s.MaxQueuedSubmissions, s.MaxQueuedSubmissionsPerUser)
+	for i := 0; i < s.Workers; i++ {
+		go s.processSubmissions()
+	}
 	go s.proccessDockerEvents()
+	if s.SelfCheck != SelfCheckOff {
+		var checks []SolutionCheck
+		for _, c := range s.allContests() {
+			cc, err := s.selfCheck(c, nil)
+			if err != nil {
+				return fmt.Errorf("self-check failed: %v", err)
+			}
+			checks = append(checks, cc...)
+		}
+		failed := logSelfCheck(checks)
+		if len(failed) > 0 && s.SelfCheck == SelfCheckStrict {
+			return fmt.Errorf("self-check failed: %v", failed[0].failure())
+		}
+	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/submit", s.submitHTTPHandler)
+	mux.HandleFunc("/submissions/", s.submissionsHTTPHandler)
 	mux.HandleFunc("/register", s.registerHTTPHandler)
+	mux.HandleFunc("/contests", s.contestsHTTPHandler)
+	mux.HandleFunc("/join", s.joinHTTPHandler)
 	mux.HandleFunc("/tasks", s.tasksHTTPHandler)
+	mux.HandleFunc("/languages", s.languagesHTTPHandler)
+	mux.HandleFunc("/limits", s.limitsHTTPHandler)
 	mux.HandleFunc("/scoreboard", s.scoreboardHTTPHandler)
+	mux.HandleFunc("/admin/selfcheck", s.selfCheckHTTPHandler)
+	mux.HandleFunc("/admin/unfreeze", s.unfreezeHTTPHandler)
 	return http.ListenAndServe(s.address, mux)
 }
@@ -8,8 +8,12 @@ import (
 // Submission is the input of the user defined task tests.
 type Submission struct {
 	id string
+	// The info of the submission's build, set once it's built.
+	build *BuildInfo
 	// The language of the submission.
 	Language string `json:"language"`
+	// The contest of the task, empty for the tasks registered with Server.RegisterTask.
+	Contest string `json:"contest,omitempty"`
 	// The task this submission is sent to.
 	TaskName string `json:"taskName"`
 	// The username of the submitter.
@@ -19,10 +23,12 @@ type Submission struct {
 }
 
 // UnmarshalJSON is a custom JSON unmarshaller. It's used mainly to create
-// a new executor instance based on the language field of the submission.
+// a new executor instance based on the language field of the submission, using
+// the factory registered with RegisterLanguage.
 func (s *Submission) UnmarshalJSON(d []byte) error {
 	metadata := struct {
 		Language   string          `json:"language"`
+		Contest    string          `json:"contest"`
 		TaskName   string          `json:"taskName"`
 		Username   string          `json:"username"`
 		Submission json.RawMessage `json:"submission"`
@@ -35,20 +41,18 @@ func (s *Submission) UnmarshalJSON(d []byte) error {
 
 	s.id = randomString(20)
 	s.Language = metadata.Language
+	s.Contest = metadata.Contest
 	s.TaskName = metadata.TaskName
 	s.Username = metadata.Username
 
-	switch s.Language {
-	case "go":
-		var e GoExecutor
-		err := json.Unmarshal(metadata.Submission, &e)
-		if err != nil {
-			return fmt.Errorf("failed to unmarshal language specific json: %v", err)
-		}
-		s.Executor = &e
-	default:
-		return fmt.Errorf("unsupported language %v", s.Language)
+	e, err := newExecutor(s.Language)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(metadata.Submission, e); err != nil {
+		return fmt.Errorf("failed to unmarshal language specific json: %v", err)
 	}
+	s.Executor = e
 
 	return nil
 }
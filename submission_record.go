@@ -0,0 +1,165 @@
+package godge
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// The possible states of a submission.
+const (
+	// StatusQueued means that the submission is waiting for a free worker.
+	StatusQueued = "queued"
+	// StatusRunning means that the submission's tests are being executed.
+	StatusRunning = "running"
+	// StatusDone means that the submission was judged and its result is available.
+	StatusDone = "done"
+)
+
+// submissionRecord is the persisted state of a submission. It's used to answer
+// the status requests of the asynchronous submissions.
+type submissionRecord struct {
+	ID          string      `db:"id"`
+	Contest     string      `db:"contest"`
+	Username    string      `db:"username"`
+	TaskName    string      `db:"task_name"`
+	Language    string      `db:"language"`
+	Status      string      `db:"status"`
+	Passed      bool        `db:"passed"`
+	Error       string      `db:"error"`
+	Tests       testResults `db:"tests"`
+	Build       buildInfo   `db:"build"`
+	Score       int         `db:"score"`
+	MaxScore    int         `db:"max_score"`
+	SubmittedAt time.Time   `db:"submitted_at"`
+}
+
+// testResults is stored in the database as JSON.
+type testResults []TestResult
+
+// Value implements the driver.Valuer interface.
+func (t testResults) Value() (driver.Value, error) {
+	b, err := json.Marshal([]TestResult(t))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (t *testResults) Scan(src interface{}) error {
+	var b []byte
+	switch v := src.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("unsupported type %T for test results", src)
+	}
+	return json.Unmarshal(b, (*[]TestResult)(t))
+}
+
+// buildInfo is stored in the database as JSON. A missing info is stored as NULL.
+type buildInfo struct {
+	*BuildInfo
+}
+
+// Value implements the driver.Valuer interface.
+func (b buildInfo) Value() (driver.Value, error) {
+	if b.BuildInfo == nil {
+		return nil, nil
+	}
+	bs, err := json.Marshal(b.BuildInfo)
+	if err != nil {
+		return nil, err
+	}
+	return string(bs), nil
+}
+
+// Scan implements the sql.Scanner interface.
+func (b *buildInfo) Scan(src interface{}) error {
+	var bs []byte
+	switch v := src.(type) {
+	case nil:
+		b.BuildInfo = nil
+		return nil
+	case string:
+		bs = []byte(v)
+	case []byte:
+		bs = v
+	default:
+		return fmt.Errorf("unsupported type %T for build info", src)
+	}
+	b.BuildInfo = &BuildInfo{}
+	return json.Unmarshal(bs, b.BuildInfo)
+}
+
+func (r *submissionRecord) save(db *sqlx.DB) error {
+	_, err := db.NamedExec(`INSERT INTO submissions (id, contest, username, task_name, language, status, passed, error, tests, build, score, max_score, submitted_at)
+		VALUES (:id, :contest, :username, :task_name, :language, :status, :passed, :error, :tests, :build, :score, :max_score, :submitted_at)`, r)
+	return err
+}
+
+func (r *submissionRecord) update(db *sqlx.DB) error {
+	_, err := db.NamedExec("UPDATE submissions SET status=:status, passed=:passed, error=:error, tests=:tests, build=:build, score=:score, max_score=:max_score WHERE id=:id", r)
+	return err
+}
+
+func (r *submissionRecord) delete(db *sqlx.DB) error {
+	_, err := db.Exec("DELETE FROM submissions WHERE id=?", r.ID)
+	return err
+}
+
+// response converts the record to the status response returned to the user.
+func (r *submissionRecord) response() SubmissionStatus {
+	ret := SubmissionStatus{
+		ID:       r.ID,
+		TaskName: r.TaskName,
+		Status:   r.Status,
+	}
+	if r.Status == StatusDone {
+		ret.Result = &SubmissionResponse{
+			Passed:   r.Passed,
+			Error:    r.Error,
+			Tests:    r.Tests,
+			Build:    r.Build.BuildInfo,
+			Score:    r.Score,
+			MaxScore: r.MaxScore,
+		}
+	}
+	return ret
+}
+
+// The error of the submissions that were queued or running when the server stopped.
+const interruptedSubmissionError = "interrupted by a server restart, please submit again"
+
+var submissionQ submissionQuery = submissionQuery{}
+
+type submissionQuery struct{}
+
+func (*submissionQuery) find(db *sqlx.DB, id string) (*submissionRecord, error) {
+	r := &submissionRecord{}
+	if err := db.Get(r, "SELECT * FROM submissions WHERE id=?", id); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// failInterrupted marks the submissions that were queued or running when the server
+// stopped as done, as they won't be judged anymore. It returns the number of marked
+// submissions.
+func (*submissionQuery) failInterrupted(db *sqlx.DB) (int64, error) {
+	res, err := db.Exec("UPDATE submissions SET status=?, passed=?, error=? WHERE status IN (?,?)",
+		StatusDone, false, interruptedSubmissionError, StatusQueued, StatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -1,6 +1,20 @@
 package godge
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// The maximum number of bytes captured from the stdout or stderr of a test.
+	maxCapturedOutput = 64 * 1024
+	// DefaultTestTimeout is the timeout of the tests that don't specify one.
+	DefaultTestTimeout = time.Minute
+	// How long to wait for a timed out test to return after stopping its container.
+	timeoutGracePeriod = 5 * time.Second
+)
 
 // Test defines on of the tests of a certain task.
 type Test struct {
@@ -9,28 +23,293 @@ type Test struct {
 	Name string
 	// The actuall test. It takes a submission as an input (along with its excutor)
 	// and should return a descriptive error when the submission don't pass the test.
-	Func func(*Submission) error
+	// The context is canceled when the test times out, and the test should return
+	// as soon as possible afterwards.
+	Func func(context.Context, *Submission) error
+	// The maximum duration of the test. The submission is stopped and gets a
+	// "Time Limit Exceeded" verdict when it's exceeded. Defaults to DefaultTestTimeout.
+	Timeout time.Duration
+	// If set, the stdout and stderr of the last execution in the test are returned
+	// to the user along with the test result.
+	CaptureOutput bool
+	// The points earned by passing the test. If none of the task's tests has points,
+	// the task is worth a single point that's earned by passing all of them.
+	Points int
 }
 
 // Task defines a group of related tests. The user needs to pass all the tests to pass
-// the task and get its point on the scoreboard.
+// the task. The user's best score of the task is shown on the scoreboard, which is
+// the sum of the points of the passed tests (see Test.Points).
 type Task struct {
 	// The name of the task that the user will use to submit their submission.
 	Name string `json:"name"`
 	// A description of what's required in order to pass the task.
 	Desc string `json:"desc"`
+	// The languages accepted by the task. All the registered languages are accepted
+	// if it's empty.
+	Languages []string `json:"languages,omitempty"`
 	// A group of tests that a submission needs to pass in order to pass the task.
 	Tests []Test `json:"-"`
+	// The maximum duration of running all the tests. Zero means that only the
+	// per test timeouts apply.
+	Timeout time.Duration `json:"-"`
+	// The resources available to the submission's container. DefaultResourceLimits
+	// are used if it's nil.
+	Limits *ResourceLimits `json:"-"`
+	// The networking mode of the submission's container (NetworkNone, NetworkRestricted
+	// or NetworkOpen). Networking is disabled by default.
+	Network string `json:"-"`
+	// The networking mode of the container building the submission (e.g. installing
+	// its dependencies). It defaults to the server's BuildNetwork.
+	BuildNetwork string `json:"-"`
+	// The TCP ports of the submission that the tests need to reach (e.g. a web server's
+	// port). Use Executor.Address to get their reachable address. Exposing ports
+	// requires networking to be enabled.
+	Ports []int `json:"-"`
+	// How to build and run the submissions in the "docker" language. The task only
+	// accepts docker submissions if it's set.
+	Docker *DockerConfig `json:"-"`
+	// The docker image used to build and run the Go submissions of this task (e.g.
+	// "golang:1.22"). Defaults to the server's GoImage.
+	GoImage string `json:"-"`
+	// A solution that must pass all the tests, and wrong solutions that must fail at
+	// least one of them. They're checked by the server's self-check to catch tests
+	// that nobody can pass, or that accept wrong solutions.
+	Reference    *Solution  `json:"-"`
+	BadSolutions []Solution `json:"-"`
 }
 
-// Execute runs the submission against all the tests. The error returned is the error
-// retured by all the tests.
-func (t *Task) execute(s *Submission) error {
-	var errs Errors
+// TestResult is the result of running a single test against a submission. It's
+// exposed to be used by the command line client.
+type TestResult struct {
+	Name    string `json:"name"`
+	Verdict string `json:"verdict"`
+	// Why the test failed, empty if it passed.
+	Message string `json:"message,omitempty"`
+	// The points earned by passing the test.
+	Points   int           `json:"points,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// Only captured if the test asks for it.
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+}
+
+// acceptsLanguage returns whether the task accepts submissions in the language.
+func (t *Task) acceptsLanguage(language string) bool {
+	if language == dockerLanguage && t.Docker == nil {
+		return false
+	}
+	if len(t.Languages) == 0 {
+		return true
+	}
+	for _, l := range t.Languages {
+		if l == language {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedLanguages returns the supported languages that the task accepts.
+func (t *Task) acceptedLanguages() []string {
+	var ret []string
+	for _, l := range supportedLanguages() {
+		if t.acceptsLanguage(l) {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+// weighted returns whether the task's tests have points.
+func (t *Task) weighted() bool {
+	for _, test := range t.Tests {
+		if test.Points > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// maxPoints returns the points earned by passing all the tests.
+func (t *Task) maxPoints() int {
+	if !t.weighted() {
+		return 1
+	}
+	total := 0
 	for _, test := range t.Tests {
-		if err := test.Func(s); err != nil {
+		total += test.Points
+	}
+	return total
+}
+
+// points returns the points earned by the submission given the results of its tests
+// and the error of the whole submission.
+func (t *Task) points(results []TestResult, err error) int {
+	if !t.weighted() {
+		if err == nil {
+			return 1
+		}
+		return 0
+	}
+	total := 0
+	for _, r := range results {
+		total += r.Points
+	}
+	return total
+}
+
+// Run builds the submission and runs it against all the tests, the same way the
+// server judges it. It returns the result of each test, and the error returned by
+// the failed tests. It's exposed to test the tasks themselves, e.g. with the fake
+// executors of the godgetest package.
+func (t *Task) Run(s *Submission) ([]TestResult, error) {
+	return t.execute(s, nil)
+}
+
+// execute runs the submission against all the tests. It returns the result of each
+// test, and the error returned is the error retured by all the tests. The progress
+// of the tests is reported to the progress func if it's not nil.
+func (t *Task) execute(s *Submission, progress func(SubmissionEvent)) ([]TestResult, error) {
+	if progress == nil {
+		progress = func(SubmissionEvent) {}
+	}
+
+	// The submission is built once upfront, so that the build doesn't count towards
+	// the time limits of the tests.
+	progress(SubmissionEvent{Type: EventBuilding})
+	build, err := s.Executor.Build()
+	s.build = build
+	if err != nil {
+		progress(SubmissionEvent{Type: EventBuilt, Build: build, Error: err.Error()})
+		return nil, fmt.Errorf("build failed: %v", err)
+	}
+	progress(SubmissionEvent{Type: EventBuilt, Build: build})
+
+	ctx := context.Background()
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	var errs Errors
+	var results []TestResult
+	for i, test := range t.Tests {
+		progress(SubmissionEvent{Type: EventTestStarted, Test: test.Name})
+		start := time.Now()
+		timedOut, leaked, err := runTest(ctx, test, s)
+		res := TestResult{
+			Name:     test.Name,
+			Verdict:  passedVerdict,
+			Duration: time.Since(start),
+		}
+		if test.CaptureOutput {
+			res.Stdout, res.Stderr = captureOutput(s.Executor)
+		}
+		if err != nil {
+			res.Verdict = failedVerdict
+			res.Message = err.Error()
+			de, _ := s.Executor.(dockerExecutor)
+			if timedOut {
+				res.Verdict = timeLimitExceededVerdict
+			} else if de != nil && de.oomKilled() {
+				res.Verdict = memoryLimitExceededVerdict
+			} else if de != nil {
+				if output, failed := de.compilationError(); failed {
+					res.Verdict = compilationErrorVerdict
+					res.Message = output
+				}
+			}
 			errs = append(errs, fmt.Errorf("test '%v' failed: %v", test.Name, err))
+			progress(SubmissionEvent{Type: EventTestFailed, Test: test.Name, Error: err.Error(), TestResult: &res})
+		} else {
+			res.Points = test.Points
+			progress(SubmissionEvent{Type: EventTestPassed, Test: test.Name, TestResult: &res})
+		}
+		results = append(results, res)
+		if leaked {
+			// The test is still using the executor, so the remaining tests can't run on it.
+			for _, skipped := range t.Tests[i+1:] {
+				res := TestResult{
+					Name:    skipped.Name,
+					Verdict: timeLimitExceededVerdict,
+					Message: fmt.Sprintf("time limit exceeded: test '%v' didn't stop after timing out", test.Name),
+				}
+				errs = append(errs, fmt.Errorf("test '%v' failed: %v", skipped.Name, res.Message))
+				progress(SubmissionEvent{Type: EventTestFailed, Test: skipped.Name, Error: res.Message, TestResult: &res})
+				results = append(results, res)
+			}
+			break
+		}
+	}
+	return results, errs.ErrorOrNil()
+}
+
+// runTest runs a single test enforcing its timeout and the deadline of the parent
+// context. If the test times out, the submission is stopped and the first returned
+// bool is true. The second one is true if the test didn't return within the grace
+// period after timing out, in which case it might still be using the executor.
+func runTest(ctx context.Context, test Test, s *Submission) (bool, bool, error) {
+	timeout := test.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTestTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// A task timeout that expired in a previous test.
+	if ctx.Err() != nil {
+		return true, false, fmt.Errorf("time limit exceeded: the task's time limit was reached before running the test")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- test.Func(ctx, s)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil && ctx.Err() == context.DeadlineExceeded {
+			return true, false, fmt.Errorf("time limit exceeded: %v", err)
 		}
+		return false, false, err
+	case <-ctx.Done():
+	}
+
+	if err := s.Executor.Stop(); err != nil {
+		log.Printf("failed to stop timed out submission %v: %v", s.id, err)
+	}
+	select {
+	case <-done:
+		return true, false, fmt.Errorf("time limit exceeded")
+	case <-time.After(timeoutGracePeriod):
+		log.Printf("test '%v' didn't return %v after timing out", test.Name, timeoutGracePeriod)
+		return true, true, fmt.Errorf("time limit exceeded")
+	}
+}
+
+// captureOutput returns the (truncated) stdout and stderr of the last execution of
+// the executor, if any.
+func captureOutput(e Executor) (string, string) {
+	if de, ok := e.(dockerExecutor); ok && de.containerID() == "" {
+		return "", ""
+	}
+	stdout, err := e.Stdout()
+	if err != nil {
+		stdout = err.Error()
+	}
+	stderr, err := e.Stderr()
+	if err != nil {
+		stderr = err.Error()
+	}
+	return truncateOutput(stdout), truncateOutput(stderr)
+}
+
+// truncateOutput truncates the output of a submission to maxCapturedOutput bytes.
+func truncateOutput(s string) string {
+	if len(s) > maxCapturedOutput {
+		return s[:maxCapturedOutput] + "\n... (truncated)"
 	}
-	return errs.ErrorOrNil()
+	return s
 }
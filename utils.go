@@ -1,11 +1,8 @@
 package godge
 
 import (
-	"archive/zip"
-	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
@@ -27,8 +24,9 @@ func randomString(n int) string {
 	return string(b)
 }
 
-func unzipToTmpDir(b []byte) (string, error) {
-	tdir, err := ioutil.TempDir("", "godge")
+// unzipToTmpDir extracts the archive into a new dir under root, enforcing the limits.
+func unzipToTmpDir(b []byte, root string, limits ArchiveLimits) (string, error) {
+	tdir, err := ioutil.TempDir(root, workDirPrefix)
 	if err != nil {
 		return "", fmt.Errorf("failed to create a tmp dir: %v", err)
 	}
@@ -36,51 +34,9 @@ func unzipToTmpDir(b []byte) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to eval symlinks: %v", err)
 	}
-	r := bytes.NewReader(b)
-	zr, err := zip.NewReader(r, r.Size())
-	if err != nil {
-		return "", fmt.Errorf("failed to create a new zip reader: %v", err)
-	}
-
-	extractAndWriteFile := func(f *zip.File) error {
-		rc, err := f.Open()
-		if err != nil {
-			return err
-		}
-		defer func() {
-			if err := rc.Close(); err != nil {
-				panic(err)
-			}
-		}()
-
-		path := filepath.Join(tdir, f.Name)
-
-		if f.FileInfo().IsDir() {
-			os.MkdirAll(path, f.Mode())
-		} else {
-			os.MkdirAll(filepath.Dir(path), f.Mode())
-			f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
-			if err != nil {
-				return err
-			}
-			defer func() {
-				if err := f.Close(); err != nil {
-					panic(err)
-				}
-			}()
-
-			_, err = io.Copy(f, rc)
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	}
-
-	for _, f := range zr.File {
-		if err := extractAndWriteFile(f); err != nil {
-			return "", err
-		}
+	if err := extractArchive(b, tdir, limits); err != nil {
+		os.RemoveAll(tdir)
+		return "", err
 	}
 	return tdir, nil
 }